@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/pipe"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+func commands(p *Plumber) []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "run",
+			Usage: "Sync the source to the target directory continuously with the given interval, which is also the default without a command.",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.New(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "sync",
+			Usage: "Sync the source to the target directory with a single cycle.",
+			Flags: pipe.SyncFlags,
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.New(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "Print the state file, the applied revision, the pin, the lock and whether the target directory has been modified.",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewStatus(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "diff",
+			Usage: "Print the changes between the source and the target directory without touching the target.",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewDiff(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:      "render",
+			Usage:     "Render a single template to the standard output, relative to the root directory of the source or the local filesystem.",
+			ArgsUsage: "<file>",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewRender(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "validate",
+			Usage: "Check the flags, the access to the source and render all the templates with the validation hooks without touching the target.",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewValidate(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "rollback",
+			Usage: "Restore the target directory to a previous revision from the history and pin it until it is unpinned.",
+			Flags: pipe.RollbackFlags,
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewRollback(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+		{
+			Name:  "unpin",
+			Usage: "Release the pinned revision and apply the latest revision of the source again.",
+			Action: func(ctx *cli.Context) error {
+				return pipe.TL.RunJobs(
+					pipe.NewUnpin(p).SetCliContext(ctx).Job(),
+				)
+			},
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package adapter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// isInRootDirectory checks whether the given path relative to the working directory is inside the root directory.
+func isInRootDirectory(root string, path string) bool {
+	rel, err := filepath.Rel(filepath.Join("/", root), filepath.Join("/", path))
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// validateWorkingDirectory refuses a working directory that overlaps the given source directories or the target directories
+// of the mappings, since the adapter removes the working directory to start from a clean copy.
+func validateWorkingDirectory(ctx *internal.ServiceCtx, workingDirectory string, sources ...string) error {
+	wd := operations.NewFile(workingDirectory)
+
+	for _, source := range sources {
+		if wd.Overlaps(operations.NewFile(source)) {
+			return fmt.Errorf("Working directory can not overlap the source directory: %s -> %s", workingDirectory, source)
+		}
+	}
+
+	for _, m := range ctx.Mappings {
+		if wd.Overlaps(operations.NewFile(m.TargetDirectory)) {
+			return fmt.Errorf("Working directory can not overlap the target directory of mapping %s: %s -> %s", m, workingDirectory, m.TargetDirectory)
+		}
+	}
+
+	return nil
+}
+
+// removedFiles returns the files that exist in the previous list but not in the next one.
+func removedFiles(previous []string, next []string) []string {
+	removed := []string{}
+
+	for _, file := range previous {
+		if !slices.Contains(next, file) {
+			removed = append(removed, file)
+		}
+	}
+
+	return removed
+}
+
+// syncDelete removes the target counterparts of the given files in the mapping, which are relative to the working directory.
+func syncDelete(t *Task[any], ctx *internal.ServiceCtx, m *internal.Mapping, files []string) error {
+	for _, file := range files {
+		if !isInRootDirectory(m.RootDirectory, file) {
+			t.Log.Debugf("File is not in the root directory: %s", file)
+
+			continue
+		}
+
+		if ctx.Provides != nil && ctx.Provides(file) {
+			t.Log.Debugf("File is still provided by another layer: %s", file)
+
+			continue
+		}
+
+		path, err := filepath.Rel(m.RootDirectory, fmt.Sprintf("/%s", file))
+		if err != nil {
+			return err
+		}
+
+		tf := operations.NewFile(m.TargetDirectory, path)
+
+		if slices.Contains(m.TemplateFiles, tf.Ext()) {
+			nf := operations.NewFile(m.TargetDirectory, strings.TrimSuffix(path, tf.Ext()))
+			t.Log.Debugf("This is a template file so path will be adjusted: %s -> %s", tf.Abs(), nf.Abs())
+			tf = nf
+		}
+
+		if !tf.Exists() {
+			t.Log.Warnf("File already does not exists: %s", tf.Abs())
+
+			continue
+		}
+
+		ctx.Changes.Add(internal.Change{
+			Action: internal.CHANGE_ACTION_DELETE,
+			Path:   tf.Abs(),
+		})
+
+		if ctx.Flags.DryRun {
+			t.Log.Infof("File would be deleted: %s", tf.Abs())
+
+			continue
+		}
+
+		if err := tf.Remove(); err != nil {
+			return err
+		}
+
+		t.Log.Warnf("File deleted: %s", tf.Abs())
+
+		ls, err := tf.ReadDir()
+		if err != nil {
+			return err
+		}
+		if len(ls) == 0 && m.SyncDeleteEmptyDirectories && tf.Cwd() != filepath.Clean(m.TargetDirectory) {
+			err = os.Remove(tf.Cwd())
+			if err != nil {
+				return err
+			}
+
+			ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_DELETE,
+				Path:   tf.Cwd(),
+			})
+
+			t.Log.Warnf("Empty directory deleted: %s", tf.Cwd())
+		}
+	}
+
+	return nil
+}
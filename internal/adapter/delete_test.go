@@ -0,0 +1,67 @@
+package adapter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+)
+
+func TestValidateWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.Symlink(filepath.Join(dir, "source"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"source", "target", "working"} {
+		if err := os.Mkdir(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		working string
+		source  string
+		target  string
+		err     bool
+	}{
+		{name: "separate directories", working: "working", source: "source", target: "target"},
+		{name: "sibling with a common prefix", working: "source-working", source: "source", target: "target"},
+		{name: "same as the source", working: "source", source: "source", target: "target", err: true},
+		{name: "inside of the source", working: "source/.beamer", source: "source", target: "target", err: true},
+		{name: "contains the source", working: ".", source: "source", target: "target", err: true},
+		{name: "source through a symbolic link", working: "link", source: "source", target: "target", err: true},
+		{name: "same as the target", working: "target", source: "source", target: "target", err: true},
+		{name: "inside of the target", working: "target/.beamer", source: "source", target: "target", err: true},
+		{name: "contains the target", working: "working", source: "source", target: "working/target", err: true},
+		{name: "without a source", working: "working", target: "target"},
+		{name: "same as the target without a source", working: "target", target: "target", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := &internal.ServiceCtx{
+				Mappings: []*internal.Mapping{{Name: "default", TargetDirectory: filepath.Join(dir, test.target)}},
+			}
+
+			sources := []string{}
+			if test.source != "" {
+				sources = append(sources, filepath.Join(dir, test.source))
+			}
+
+			err := validateWorkingDirectory(ctx, filepath.Join(dir, test.working), sources...)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -1,19 +1,25 @@
 package adapter
 
 import (
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/urfave/cli/v2"
 	"gitlab.kilic.dev/docker/beamer/internal"
@@ -25,7 +31,10 @@ import (
 type BeamerGitAuthMethod = string
 
 const (
-	BEAMER_GIT_AUTH_METHOD_SSH BeamerGitAuthMethod = "ssh"
+	BEAMER_GIT_AUTH_METHOD_NONE  BeamerGitAuthMethod = "none"
+	BEAMER_GIT_AUTH_METHOD_SSH   BeamerGitAuthMethod = "ssh"
+	BEAMER_GIT_AUTH_METHOD_BASIC BeamerGitAuthMethod = "basic"
+	BEAMER_GIT_AUTH_METHOD_TOKEN BeamerGitAuthMethod = "token"
 )
 
 type BeamerGitSshHostKeyVerification = string
@@ -36,9 +45,18 @@ const (
 	BEAMER_GIT_SSH_HOST_KEY_VERIFICATION_FIXED   BeamerGitSshHostKeyVerification = "fixed"
 )
 
+type BeamerGitVerifySignature = string
+
+const (
+	BEAMER_GIT_VERIFY_SIGNATURE_NONE   BeamerGitVerifySignature = "none"
+	BEAMER_GIT_VERIFY_SIGNATURE_COMMIT BeamerGitVerifySignature = "commit"
+	BEAMER_GIT_VERIFY_SIGNATURE_TAG    BeamerGitVerifySignature = "tag"
+)
+
 const category_git = "Adapter Git"
 
 var gitAdapterFlags = &GitAdapterConfig{}
+var gitAdapterSparseCheckoutPaths = cli.NewStringSlice()
 
 var GitAdapterFlags = []cli.Flag{
 	&cli.StringFlag{
@@ -53,20 +71,83 @@ var GitAdapterFlags = []cli.Flag{
 
 	&cli.StringFlag{
 		Category:    category_git,
-		Name:        "git-branch",
-		Usage:       "Git branch to clone.",
+		Name:        "git-ref",
+		Aliases:     []string{"git-branch"},
+		Usage:       "Git reference to sync, which can be a branch, a tag, a full commit hash or a semver constraint that resolves to the highest matching tag.",
 		Required:    false,
 		Value:       "HEAD",
-		EnvVars:     []string{"BEAMER_GIT_BRANCH"},
-		Destination: &gitAdapterFlags.Branch,
+		EnvVars:     []string{"BEAMER_GIT_REF", "BEAMER_GIT_BRANCH"},
+		Destination: &gitAdapterFlags.Ref,
+	},
+
+	&cli.IntFlag{
+		Category:    category_git,
+		Name:        "git-depth",
+		Usage:       "Limit the fetched history to the given number of commits from the tip of the reference, where 0 fetches the full history.",
+		Required:    false,
+		Value:       0,
+		EnvVars:     []string{"BEAMER_GIT_DEPTH"},
+		Destination: &gitAdapterFlags.Depth,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_git,
+		Name:        "git-sparse-checkout",
+		Usage:       "Only check out the root directory and the additional sparse checkout paths from the repository.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_GIT_SPARSE_CHECKOUT"},
+		Destination: &gitAdapterFlags.SparseCheckout,
+	},
+
+	&cli.StringSliceFlag{
+		Category:    category_git,
+		Name:        "git-sparse-checkout-paths",
+		Usage:       "Additional directories relative to the repository to check out with the sparse checkout.",
+		Required:    false,
+		EnvVars:     []string{"BEAMER_GIT_SPARSE_CHECKOUT_PATHS"},
+		Destination: gitAdapterSparseCheckoutPaths,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_git,
+		Name:        "git-submodules",
+		Usage:       "Recursively initialize and update the submodules of the repository with the same authentication method.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_GIT_SUBMODULES"},
+		Destination: &gitAdapterFlags.Submodules,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_git,
+		Name:        "git-lfs",
+		Usage:       "Resolve the Git LFS pointer files of the repository into their content, which is not supported with SSH authentication since the objects are downloaded over HTTP.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_GIT_LFS"},
+		Destination: &gitAdapterFlags.Lfs,
 	},
 
 	&cli.StringFlag{
 		Category:    category_git,
-		Name:        "git-auth-method",
-		Usage:       "Authentication method to use. enum(none, ssh)",
+		Name:        "git-lfs-endpoint",
+		Usage:       "Git LFS endpoint to download the objects from, which is derived from the repository URL when not provided.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_LFS_ENDPOINT"},
+		Destination: &gitAdapterFlags.LfsEndpoint,
+	},
+
+	&cli.StringFlag{
+		Category: category_git,
+		Name:     "git-auth-method",
+		Usage: fmt.Sprintf(
+			"Authentication method to use. enum(%v)",
+			[]string{BEAMER_GIT_AUTH_METHOD_NONE, BEAMER_GIT_AUTH_METHOD_SSH, BEAMER_GIT_AUTH_METHOD_BASIC, BEAMER_GIT_AUTH_METHOD_TOKEN},
+		),
 		Required:    false,
-		Value:       "none",
+		Value:       BEAMER_GIT_AUTH_METHOD_NONE,
 		EnvVars:     []string{"BEAMER_GIT_AUTH_METHOD"},
 		Destination: &gitAdapterFlags.AuthMethod,
 	},
@@ -113,49 +194,182 @@ var GitAdapterFlags = []cli.Flag{
 		EnvVars:     []string{"BEAMER_GIT_SSH_HOST_KEY"},
 		Destination: &gitAdapterFlags.SshHostKey,
 	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-username",
+		Usage:       "Username to use for basic authentication.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_USERNAME"},
+		Destination: &gitAdapterFlags.Username,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-password",
+		Usage:       "Password to use for basic authentication.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_PASSWORD"},
+		Destination: &gitAdapterFlags.Password,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-password-file",
+		Usage:       "File to read the password for basic authentication from.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_PASSWORD_FILE"},
+		Destination: &gitAdapterFlags.PasswordFile,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-token",
+		Usage:       "Bearer token to use for token authentication.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_TOKEN"},
+		Destination: &gitAdapterFlags.Token,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-token-file",
+		Usage:       "File to read the bearer token for token authentication from.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_TOKEN_FILE"},
+		Destination: &gitAdapterFlags.TokenFile,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-ca-bundle",
+		Usage:       "Additional CA bundle to trust for HTTPS repositories, either as a file or PEM encoded.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_CA_BUNDLE"},
+		Destination: &gitAdapterFlags.CaBundle,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_git,
+		Name:        "git-insecure-skip-tls-verify",
+		Usage:       "Skip the TLS verification for HTTPS repositories.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_GIT_INSECURE_SKIP_TLS_VERIFY"},
+		Destination: &gitAdapterFlags.InsecureSkipTlsVerify,
+	},
+
+	&cli.StringFlag{
+		Category: category_git,
+		Name:     "git-verify-signature",
+		Usage: fmt.Sprintf(
+			"Verify the signature of the commit or the annotated tag before applying a revision. enum(%v)",
+			[]string{BEAMER_GIT_VERIFY_SIGNATURE_NONE, BEAMER_GIT_VERIFY_SIGNATURE_COMMIT, BEAMER_GIT_VERIFY_SIGNATURE_TAG},
+		),
+		Required:    false,
+		Value:       BEAMER_GIT_VERIFY_SIGNATURE_NONE,
+		EnvVars:     []string{"BEAMER_GIT_VERIFY_SIGNATURE"},
+		Destination: &gitAdapterFlags.VerifySignature,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-gpg-keyring",
+		Usage:       "Armored OpenPGP public keys that are allowed to sign, either as a file or directly.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_GPG_KEYRING"},
+		Destination: &gitAdapterFlags.GpgKeyring,
+	},
+
+	&cli.StringFlag{
+		Category:    category_git,
+		Name:        "git-ssh-allowed-signers",
+		Usage:       "SSH keys that are allowed to sign in the allowed signers format of ssh-keygen, either as a file or directly, where the namespaces, valid-after and valid-before options are supported and certificate authorities are not.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_GIT_SSH_ALLOWED_SIGNERS"},
+		Destination: &gitAdapterFlags.SshAllowedSigners,
+	},
 }
 
 type GitAdapterConfig struct {
 	Repository             string `validate:"required"`
-	Branch                 string
-	AuthMethod             BeamerGitAuthMethod `validate:"oneof=none ssh"`
+	Ref                    string
+	Depth                  int `validate:"gte=0"`
+	SparseCheckout         bool
+	SparseCheckoutPaths    []string
+	Submodules             bool
+	Lfs                    bool
+	LfsEndpoint            string
+	AuthMethod             BeamerGitAuthMethod `validate:"oneof=none ssh basic token"`
 	SshPrivateKey          string              `validate:"required_if=Inner.AuthMethod ssh"`
 	SshPrivateKeyPassword  string
 	SshHostKeyVerification string `validate:"oneof=default ignore fixed"`
 	SshHostKey             string `validate:"required_if=Inner.SshHostKeyVerification fixed"`
+	Username               string `validate:"required_if=Inner.AuthMethod basic"`
+	Password               string
+	PasswordFile           string
+	Token                  string
+	TokenFile              string
+	CaBundle               string
+	InsecureSkipTlsVerify  bool
+	VerifySignature        BeamerGitVerifySignature `validate:"oneof=none commit tag"`
+	GpgKeyring             string
+	SshAllowedSigners      string
 }
 
 type GitAdapter struct {
-	ctx              *internal.ServiceCtx
-	tl               *TaskList[any]
-	repository       *git.Repository
-	authMethod       transport.AuthMethod
-	config           *GitAdapterConfig
-	workingDirectory string
-	state            *GitAdapterState
+	ctx               *internal.ServiceCtx
+	tl                *TaskList[any]
+	repository        *git.Repository
+	ref               *GitRef
+	authMethod        transport.AuthMethod
+	caBundle          []byte
+	sparseDirectories []string
+	lfs               *gitLfsClient
+	verifier          *gitSignatureVerifier
+	config            *GitAdapterConfig
+	workingDirectory  string
+	state             *GitAdapterState
 }
 
 type GitAdapterState struct {
+	Ref        string `json:"ref,omitempty"`
 	LastCommit string `json:"lastCommit"`
+	// Files is the manifest of the working tree, which is used for syncing the deleted files when the last commit is not reachable anymore.
+	Files []string `json:"files,omitempty"`
 }
 
 var _ Adapter = (*GitAdapter)(nil)
 
 func NewGitAdapter(p *Plumber, ctx *internal.ServiceCtx) (*GitAdapter, error) {
+	gitAdapterFlags.SparseCheckoutPaths = gitAdapterSparseCheckoutPaths.Value()
+
+	return newGitAdapter(p, ctx, gitAdapterFlags)
+}
+
+func newGitAdapter(p *Plumber, ctx *internal.ServiceCtx, config *GitAdapterConfig) (*GitAdapter, error) {
 	log := ctx.Log.WithField(LOG_FIELD_CONTEXT, "adapter").WithField(LOG_FIELD_STATUS, "git")
 
 	adapter := &GitAdapter{
 		ctx:              ctx,
-		config:           gitAdapterFlags,
+		config:           config,
 		workingDirectory: ctx.WorkingDirectory,
 		tl:               (&TaskList[any]{}).New(p),
 	}
 
-	switch gitAdapterFlags.AuthMethod {
+	switch config.AuthMethod {
 	case BEAMER_GIT_AUTH_METHOD_SSH:
 		var key []byte
 
-		if f := operations.NewFile(gitAdapterFlags.SshPrivateKey); f.IsFile() {
+		if f := operations.NewFile(config.SshPrivateKey); f.IsFile() {
 			k, err := f.ReadFile()
 			if err != nil {
 				return nil, err
@@ -164,7 +378,7 @@ func NewGitAdapter(p *Plumber, ctx *internal.ServiceCtx) (*GitAdapter, error) {
 
 			log.Debug("Using SSH private key as file.")
 		} else {
-			k, err := base64.StdEncoding.DecodeString(gitAdapterFlags.SshPrivateKey)
+			k, err := base64.StdEncoding.DecodeString(config.SshPrivateKey)
 			if err != nil {
 				return nil, err
 			}
@@ -175,18 +389,18 @@ func NewGitAdapter(p *Plumber, ctx *internal.ServiceCtx) (*GitAdapter, error) {
 
 		log.Infof("Using SSH authentication for git.")
 
-		am, err := ssh.NewPublicKeys("git", key, gitAdapterFlags.SshPrivateKeyPassword)
+		am, err := ssh.NewPublicKeys("git", key, config.SshPrivateKeyPassword)
 		if err != nil {
 			return nil, err
 		}
-		switch gitAdapterFlags.SshHostKeyVerification {
+		switch config.SshHostKeyVerification {
 		case BEAMER_GIT_SSH_HOST_KEY_VERIFICATION_IGNORE:
 			//nolint: gosec
 			am.HostKeyCallback = stdssh.InsecureIgnoreHostKey()
 
 			ctx.Log.Warnln("Host key verification is disabled.")
 		case BEAMER_GIT_SSH_HOST_KEY_VERIFICATION_FIXED:
-			v, err := base64.StdEncoding.DecodeString(gitAdapterFlags.SshHostKey)
+			v, err := base64.StdEncoding.DecodeString(config.SshHostKey)
 			if err != nil {
 				return nil, err
 			}
@@ -203,6 +417,162 @@ func NewGitAdapter(p *Plumber, ctx *internal.ServiceCtx) (*GitAdapter, error) {
 		}
 
 		adapter.authMethod = am
+	case BEAMER_GIT_AUTH_METHOD_BASIC:
+		password, err := readSecret(config.Password, config.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("Using basic authentication for git.")
+
+		adapter.authMethod = &http.BasicAuth{
+			Username: config.Username,
+			Password: password,
+		}
+	case BEAMER_GIT_AUTH_METHOD_TOKEN:
+		token, err := readSecret(config.Token, config.TokenFile)
+		if err != nil {
+			return nil, err
+		} else if token == "" {
+			return nil, errors.New("Token is required for token authentication")
+		}
+
+		log.Infof("Using token authentication for git.")
+
+		adapter.authMethod = &http.TokenAuth{
+			Token: token,
+		}
+	}
+
+	if config.CaBundle != "" {
+		if f := operations.NewFile(config.CaBundle); f.IsFile() {
+			bundle, err := f.ReadFile()
+			if err != nil {
+				return nil, err
+			}
+			adapter.caBundle = bundle
+
+			log.Debug("Using CA bundle as file.")
+		} else {
+			adapter.caBundle = []byte(config.CaBundle)
+
+			log.Debug("Using CA bundle directly from flag.")
+		}
+
+		if !x509.NewCertPool().AppendCertsFromPEM(adapter.caBundle) {
+			return nil, errors.New("CA bundle does not contain any valid PEM encoded certificates")
+		}
+	}
+
+	if config.InsecureSkipTlsVerify {
+		log.Warnln("TLS verification is disabled.")
+	}
+
+	if config.VerifySignature != BEAMER_GIT_VERIFY_SIGNATURE_NONE {
+		adapter.verifier = &gitSignatureVerifier{}
+
+		if config.GpgKeyring != "" {
+			if f := operations.NewFile(config.GpgKeyring); f.IsFile() {
+				keyring, err := f.ReadFile()
+				if err != nil {
+					return nil, err
+				}
+				adapter.verifier.keyring = string(keyring)
+
+				log.Debug("Using OpenPGP keyring as file.")
+			} else {
+				adapter.verifier.keyring = config.GpgKeyring
+
+				log.Debug("Using OpenPGP keyring directly from flag.")
+			}
+		}
+
+		if config.SshAllowedSigners != "" {
+			content := []byte(config.SshAllowedSigners)
+			if f := operations.NewFile(config.SshAllowedSigners); f.IsFile() {
+				c, err := f.ReadFile()
+				if err != nil {
+					return nil, err
+				}
+				content = c
+
+				log.Debug("Using SSH allowed signers as file.")
+			} else {
+				log.Debug("Using SSH allowed signers directly from flag.")
+			}
+
+			signers, err := parseGitAllowedSigners(content)
+			if err != nil {
+				return nil, err
+			}
+			adapter.verifier.allowedSigners = signers
+		}
+
+		if adapter.verifier.keyring == "" && len(adapter.verifier.allowedSigners) == 0 {
+			return nil, errors.New("Signature verification requires an OpenPGP keyring or SSH allowed signers")
+		}
+
+		log.Infof("Using signature verification for: %s", config.VerifySignature)
+	}
+
+	if config.Depth > 0 {
+		log.Infof("Using shallow clone with depth: %d", config.Depth)
+	}
+
+	if config.Submodules {
+		log.Infof("Using submodules.")
+	}
+
+	if config.Lfs {
+		// the objects are downloaded over HTTP, where the SSH credentials can not be used
+		if config.AuthMethod == BEAMER_GIT_AUTH_METHOD_SSH {
+			return nil, errors.New("Git LFS is not supported with SSH authentication, use basic or token authentication instead")
+		}
+
+		endpoint := config.LfsEndpoint
+		if endpoint == "" {
+			e, err := GitLfsEndpoint(config.Repository)
+			if err != nil {
+				return nil, err
+			}
+			endpoint = e
+		}
+
+		log.Infof("Using Git LFS endpoint: %s", endpoint)
+
+		adapter.lfs = newGitLfsClient(
+			endpoint,
+			adapter.authMethod,
+			adapter.caBundle,
+			config.InsecureSkipTlsVerify,
+			filepath.Join(ctx.WorkingDirectory, git.GitDirName, "lfs", "objects"),
+		)
+	}
+
+	if config.SparseCheckout {
+		dirs := []string{}
+		for _, m := range ctx.Mappings {
+			dirs = append(dirs, m.RootDirectory)
+		}
+
+		for _, dir := range append(dirs, config.SparseCheckoutPaths...) {
+			dir = strings.Trim(filepath.ToSlash(filepath.Clean(filepath.Join("/", dir))), "/")
+
+			// the repository root contains everything, so there is nothing to restrict
+			if dir == "" {
+				adapter.sparseDirectories = nil
+
+				break
+			}
+
+			adapter.sparseDirectories = append(adapter.sparseDirectories, dir)
+		}
+
+		if len(adapter.sparseDirectories) == 0 {
+			log.Warnln("Sparse checkout is disabled since the repository root is requested.")
+		} else {
+			log.Infof("Using sparse checkout for directories: %v", adapter.sparseDirectories)
+		}
 	}
 
 	return adapter, nil
@@ -211,24 +581,27 @@ func NewGitAdapter(p *Plumber, ctx *internal.ServiceCtx) (*GitAdapter, error) {
 func (a *GitAdapter) Init() Job {
 	return a.tl.CreateTask("init").
 		Set(func(t *Task[any]) error {
-			t.Log.Infof("Cloning repository: %s@%s", a.config.Repository, a.config.Branch)
-
-			r, err := git.PlainClone(a.workingDirectory, false, &git.CloneOptions{
-				URL:           a.config.Repository,
-				Progress:      t.Log.Writer(),
-				Auth:          a.authMethod,
-				SingleBranch:  true,
-				ReferenceName: plumbing.ReferenceName(a.config.Branch),
-			})
-			if errors.Is(err, git.ErrRepositoryAlreadyExists) {
-				t.Log.Warnf("Repository already exists. Skipping clone.")
+			r, err := git.PlainOpen(a.workingDirectory)
+			if errors.Is(err, git.ErrRepositoryNotExists) {
+				t.Log.Infof("Cloning repository: %s@%s", a.config.Repository, a.config.Ref)
 
-				r, err = git.PlainOpen(a.workingDirectory)
+				r, err = git.PlainInit(a.workingDirectory, false)
 				if err != nil {
 					return err
 				}
 
-				remote, err := r.Remote("origin")
+				if _, err := r.CreateRemote(&config.RemoteConfig{
+					Name: git.DefaultRemoteName,
+					URLs: []string{a.config.Repository},
+				}); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			} else {
+				t.Log.Warnf("Repository already exists. Skipping clone.")
+
+				remote, err := r.Remote(git.DefaultRemoteName)
 				if err != nil {
 					return err
 				}
@@ -236,20 +609,56 @@ func (a *GitAdapter) Init() Job {
 				if remote.Config().URLs[0] != a.config.Repository {
 					return fmt.Errorf("Remote repository URL does not match the provided URL: %s -> %s", remote.Config().URLs[0], a.config.Repository)
 				}
-			} else if err != nil {
-				return err
 			}
 
 			a.repository = r
 
-			ref, err := r.Head()
+			ref, err := a.resolve()
 			if err != nil {
 				return err
 			}
+
+			if err := a.fetch(t, ref); err != nil {
+				return err
+			}
+
+			if err := a.verify(t, ref); err != nil {
+				previous, e := a.appliedRef()
+				if e != nil {
+					return e
+				} else if previous == nil {
+					return fmt.Errorf("Can not verify the revision and there is no applied revision to keep: %s -> %w", ref, err)
+				}
+
+				// the applied revision is kept instead of failing the start, so the verification is retried with the next sync,
+				// where the error is reported with the first cycle since the initialization runs before it
+				t.Log.Errorf("Keeping the applied revision %s -> %v", previous, err)
+				a.ctx.Changes.AddPendingError(fmt.Errorf("Keeping the applied revision since the verification has failed: %s -> %w", previous, err))
+
+				// the applied commit might not be the tip of any reference anymore
+				if err := a.fetch(t, &GitRef{Hash: previous.Hash}); err != nil {
+					return err
+				}
+
+				ref = previous
+			}
+
+			if err := a.checkout(t, ref); err != nil {
+				return err
+			}
+
 			t.Log.Infof("Repository cloned successfully: %s", ref)
 
+			files, err := a.files()
+			if err != nil {
+				return err
+			}
+
+			a.ref = ref
 			a.state = &GitAdapterState{
-				LastCommit: ref.Hash().String(),
+				Ref:        ref.Name.String(),
+				LastCommit: ref.Hash.String(),
+				Files:      files,
 			}
 
 			return nil
@@ -260,45 +669,54 @@ func (a *GitAdapter) Init() Job {
 func (a *GitAdapter) Sync() Job {
 	return a.tl.CreateTask("sync").
 		Set(func(t *Task[any]) error {
-			t.Log.Infof("Pulling repository: %s@%s", a.config.Repository, a.config.Branch)
+			t.Log.Infof("Pulling repository: %s@%s", a.config.Repository, a.config.Ref)
 
-			w, err := a.repository.Worktree()
+			ref, err := a.resolve()
 			if err != nil {
 				return err
 			}
 
-			originalRef, err := a.repository.Head()
-			if err != nil {
-				return err
+			if ref.Hash == a.ref.Hash {
+				t.Log.Infof("Repository is already up-to-date: %s", ref)
+
+				return nil
 			}
 
-			err = w.Pull(&git.PullOptions{
-				Progress:      t.Log.Writer(),
-				Auth:          a.authMethod,
-				ReferenceName: plumbing.ReferenceName("HEAD"),
-			})
-			if errors.Is(err, git.NoErrAlreadyUpToDate) {
-				ref, err := a.repository.Head()
-				if err != nil {
-					return err
-				}
+			if err := a.fetch(t, ref); err != nil {
+				return err
+			}
 
-				t.Log.Infof("Repository is already up-to-date: %s", ref)
+			// the previous revision stays applied, so the verification is retried with the next sync
+			if err := a.verify(t, ref); err != nil {
+				t.Log.Errorf("Keeping the previous revision %s -> %v", a.ref, err)
+				a.ctx.Changes.AddError(err)
 
 				return nil
-			} else if err != nil {
+			}
+
+			if err := a.checkout(t, ref); err != nil {
 				return err
 			}
 
-			ref, err := a.repository.Head()
+			original := a.ref
+			if original.Name == ref.Name && a.isRewritten(original.Hash, ref.Hash) {
+				t.Log.Warnf("History of the repository was rewritten, discarding the local commit: %s -> %s", original, ref)
+				a.logCommit(t, "Previous commit", original.Hash)
+				a.logCommit(t, "Current commit", ref.Hash)
+			}
+
+			files, err := a.files()
 			if err != nil {
 				return err
 			}
 
-			a.state.LastCommit = ref.Hash().String()
-			a.ctx.State.SetDirty()
+			a.ref = ref
+			a.state.Ref = ref.Name.String()
+			a.state.LastCommit = ref.Hash.String()
+			a.state.Files = files
+			a.ctx.SetDirty()
 
-			t.Log.Infof("Repository pulled successfully: %s -> %s", originalRef, ref)
+			t.Log.Infof("Repository pulled successfully: %s -> %s", original, ref)
 
 			return nil
 		}).
@@ -308,135 +726,564 @@ func (a *GitAdapter) Sync() Job {
 func (a *GitAdapter) Finalize() Job {
 	return a.tl.CreateTask("finalize").
 		Set(func(t *Task[any]) error {
-			t.CreateSubtask("sync", "delete").
-				ShouldDisable(func(_ *Task[any]) bool {
-					return !(a.ctx.Flags.ForceSync || (a.ctx.Flags.SyncDelete && a.ctx.State.IsDirty()))
-				}).
-				Set(func(t *Task[any]) error {
-					f, err := a.ctx.State.Read()
-					if err != nil {
-						return err
-					}
-					state := &GitAdapterState{}
-					if f == nil {
-						t.Log.Warnf("State file does not exists.")
-
-						return nil
-					}
-
-					if err := json.Unmarshal(f, &state); err != nil {
-						t.Log.Errorf("Failed to unmarshal state file: %v", err)
-
-						return nil
-					}
-
-					oldCommit, err := a.repository.CommitObject(plumbing.NewHash(state.LastCommit))
-					if err != nil {
-						return err
-					}
-					head, err := a.repository.Head()
-					if err != nil {
-						return err
-					}
-					newCommit, err := a.repository.CommitObject(head.Hash())
-					if err != nil {
-						return err
-					}
-
-					oldTree, err := oldCommit.Tree()
-					if err != nil {
-						return err
-					}
-					newTree, err := newCommit.Tree()
-					if err != nil {
-						return err
-					}
-
-					t.Log.Debugf("Syncing deleted files: from commit %s to %s", oldCommit.Hash, newCommit.Hash)
-
-					var toDelete []string
-					err = oldTree.Files().ForEach(func(f *object.File) error {
-						if a.ctx.RootDirectory != "" && !strings.HasPrefix(f.Name, fmt.Sprintf("%s/", a.ctx.RootDirectory)) {
-							t.Log.Debugf("File is not in the root directory: %s", f.Name)
+			for _, m := range a.ctx.Mappings {
+				t.CreateSubtask("sync", "delete", m.Name).
+					ShouldDisable(func(_ *Task[any]) bool {
+						return !(a.ctx.Flags.ForceSync || (m.SyncDelete && m.State.IsDirty()))
+					}).
+					Set(func(t *Task[any]) error {
+						f, err := m.State.Read()
+						if err != nil {
+							return err
+						}
+						state := &GitAdapterState{}
+						if f == nil {
+							t.Log.Warnf("State file does not exists.")
 
 							return nil
-						} else if _, err := newTree.File(f.Name); errors.Is(err, object.ErrFileNotFound) {
-							t.Log.Debugf("File was in the old commit but does not exists in the new commit: %s", f.Name)
-							toDelete = append(toDelete, f.Name)
 						}
 
-						return nil
-					})
-					if err != nil {
-						return err
-					}
+						if err := json.Unmarshal(f, &state); err != nil {
+							t.Log.Errorf("Failed to unmarshal state file: %v", err)
 
-					if len(toDelete) == 0 {
-						t.Log.Infof("No changes found between %s and %s", oldCommit.Hash, newCommit.Hash)
+							return nil
+						}
 
-						return nil
-					}
+						// the tree of the repository does not contain the files of the submodules
+						if a.config.Submodules {
+							return a.syncDeletedFiles(t, m, state)
+						}
 
-					for _, file := range toDelete {
-						path, err := filepath.Rel(a.ctx.RootDirectory, fmt.Sprintf("/%s", file))
+						oldCommit, err := a.repository.CommitObject(plumbing.NewHash(state.LastCommit))
+						if errors.Is(err, plumbing.ErrObjectNotFound) {
+							t.Log.Warnf("Last commit is not reachable in the repository, falling back to the manifest of the working tree: %s", state.LastCommit)
+
+							return a.syncDeletedFiles(t, m, state)
+						} else if err != nil {
+							return err
+						}
+						head, err := a.repository.Head()
 						if err != nil {
 							return err
 						}
-
-						tf := operations.NewFile(a.ctx.TargetDirectory, path)
-
-						if slices.Contains(a.ctx.Flags.TemplateFiles, tf.Ext()) {
-							nf := operations.NewFile(a.ctx.TargetDirectory, strings.TrimSuffix(path, tf.Ext()))
-							t.Log.Debugf("This is a template file so path will be adjusted: %s -> %s", tf.Abs(), nf.Abs())
-							tf = nf
+						newCommit, err := a.repository.CommitObject(head.Hash())
+						if err != nil {
+							return err
 						}
 
-						if !tf.Exists() {
-							t.Log.Warnf("File already does not exists: %s", tf.Abs())
-
-							return nil
+						oldTree, err := oldCommit.Tree()
+						if err != nil {
+							return err
 						}
-
-						if err := tf.Remove(); err != nil {
+						newTree, err := newCommit.Tree()
+						if err != nil {
 							return err
 						}
 
-						t.Log.Warnf("File deleted: %s", tf.Abs())
+						t.Log.Debugf("Syncing deleted files: from commit %s to %s", oldCommit.Hash, newCommit.Hash)
+
+						var toDelete []string
+						err = oldTree.Files().ForEach(func(f *object.File) error {
+							if !isInRootDirectory(m.RootDirectory, f.Name) {
+								t.Log.Debugf("File is not in the root directory: %s", f.Name)
 
-						ls, err := tf.ReadDir()
+								return nil
+							} else if _, err := newTree.File(f.Name); errors.Is(err, object.ErrFileNotFound) {
+								t.Log.Debugf("File was in the old commit but does not exists in the new commit: %s", f.Name)
+								toDelete = append(toDelete, f.Name)
+							}
+
+							return nil
+						})
 						if err != nil {
 							return err
 						}
-						if len(ls) == 0 && a.ctx.Flags.SyncDeleteEmptyDirectories && tf.Cwd() != a.ctx.TargetDirectory {
-							err = os.Remove(tf.Cwd())
-							if err != nil {
-								return err
-							}
 
-							t.Log.Warnf("Empty directory deleted: %s", tf.Cwd())
+						if len(toDelete) == 0 {
+							t.Log.Infof("No changes found between %s and %s", oldCommit.Hash, newCommit.Hash)
+
+							return nil
 						}
-					}
 
-					return nil
-				}).
-				AddSelfToTheParentAsSequence()
+						return syncDelete(t, a.ctx, m, toDelete)
+					}).
+					AddSelfToTheParentAsSequence()
 
-			t.CreateSubtask("state", "commit").
-				Set(func(t *Task[any]) error {
-					a.ctx.State.SetClean()
+				t.CreateSubtask("state", "commit", m.Name).
+					Set(func(t *Task[any]) error {
+						m.State.SetClean()
 
-					f, err := json.Marshal(a.state)
-					if err != nil {
-						return err
-					}
+						f, err := json.Marshal(a.state)
+						if err != nil {
+							return err
+						}
 
-					t.Log.Debugf("Writing state file: %s", f)
+						t.Log.Debugf("Writing state file: %s", f)
 
-					return a.ctx.State.Write(f)
-				}).
-				AddSelfToTheParentAsSequence()
+						return m.State.Write(f)
+					}).
+					AddSelfToTheParentAsSequence()
+			}
 
 			return t.RunSubtasks()
 		}).
 		Job()
 }
+
+func (a *GitAdapter) Revision() (*Revision, error) {
+	if a.repository == nil {
+		return nil, ErrNotInitialized
+	}
+
+	head, err := a.repository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := a.repository.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &Revision{
+		Adapter:  "git",
+		Revision: commit.Hash.String(),
+		Commit:   commit.Hash.String(),
+		Time:     commit.Committer.When,
+		Author:   commit.Author.String(),
+		Message:  strings.TrimSpace(commit.Message),
+	}
+
+	if head.Name().IsBranch() {
+		revision.Branch = head.Name().Short()
+	} else if a.ref != nil && a.ref.Name != "" {
+		revision.Branch = a.ref.Name.Short()
+	}
+
+	return revision, nil
+}
+
+// resolve lists the references of the remote repository and resolves the configured reference to a commit.
+//
+// A revision that is pinned by a rollback takes precedence over the configured reference.
+func (a *GitAdapter) resolve() (*GitRef, error) {
+	if pin := a.ctx.Pinned(); pin != nil {
+		if !plumbing.IsHash(pin.Revision) {
+			return nil, fmt.Errorf("Pinned revision is not a commit: %s", pin.Revision)
+		}
+
+		a.ctx.Log.Debugf("Using the pinned revision instead of the reference: %s -> %s", a.config.Ref, pin.Revision)
+
+		return &GitRef{Hash: plumbing.NewHash(pin.Revision)}, nil
+	}
+
+	remote, err := a.repository.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := remote.List(&git.ListOptions{
+		Auth:            a.authMethod,
+		CABundle:        a.caBundle,
+		InsecureSkipTLS: a.config.InsecureSkipTlsVerify,
+		PeelingOption:   git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Can not list the references of the remote repository: %s -> %w", a.config.Repository, err)
+	}
+
+	ref, err := ResolveGitRef(refs, a.config.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	a.ctx.Log.Debugf("Resolved reference: %s -> %s", a.config.Ref, ref)
+
+	return ref, nil
+}
+
+// appliedRef returns the revision that is recorded in the state of the mappings, which is nil when nothing has been applied yet.
+func (a *GitAdapter) appliedRef() (*GitRef, error) {
+	for _, m := range a.ctx.Mappings {
+		data, err := m.State.Read()
+		if err != nil {
+			return nil, err
+		} else if data == nil {
+			continue
+		}
+
+		state := &GitAdapterState{}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("Can not parse the state of the mapping: %s -> %w", m, err)
+		}
+
+		if plumbing.IsHash(state.LastCommit) {
+			return &GitRef{Name: plumbing.ReferenceName(state.Ref), Hash: plumbing.NewHash(state.LastCommit)}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// checkout hard resets the working tree to the fetched reference.
+func (a *GitAdapter) checkout(t *Task[any], ref *GitRef) error {
+	w, err := a.repository.Worktree()
+	if err != nil {
+		return err
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{
+		Hash:                      ref.Hash,
+		Force:                     true,
+		SparseCheckoutDirectories: a.sparseDirectories,
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.config.Submodules {
+		if err := a.updateSubmodules(t, w); err != nil {
+			return err
+		}
+	}
+
+	if a.lfs != nil {
+		if err := a.resolveLfs(t, ref.Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetch fetches the resolved reference when its objects are not available locally,
+// the remote reference is force fetched, so that rewritten histories do not block the update.
+func (a *GitAdapter) fetch(t *Task[any], ref *GitRef) error {
+	if !a.hasObjects(ref) {
+		t.Log.Debugf("Fetching reference: %s", ref)
+
+		options := &git.FetchOptions{
+			RemoteName:      git.DefaultRemoteName,
+			RefSpecs:        ref.RefSpecs(),
+			Depth:           a.config.Depth,
+			Progress:        t.Log.Writer(),
+			Auth:            a.authMethod,
+			CABundle:        a.caBundle,
+			InsecureSkipTLS: a.config.InsecureSkipTlsVerify,
+			Force:           true,
+		}
+
+		err := a.repository.Fetch(options)
+		if ref.Name == "" && errors.Is(err, git.ErrExactSHA1NotSupported) {
+			t.Log.Debugf("Remote repository does not allow fetching a commit directly, fetching the branches and tags: %s", ref.Hash)
+
+			// the commit might be older than the depth of every branch, so the whole history is fetched like with --unshallow
+			options.RefSpecs = ref.ReachableRefSpecs()
+			if options.Depth > 0 {
+				options.Depth = math.MaxInt32
+			}
+
+			err = a.repository.Fetch(options)
+		}
+
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
+
+		if _, err := a.repository.CommitObject(ref.Hash); err != nil {
+			return fmt.Errorf("Commit is not reachable from the remote repository: %s -> %w", ref.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *GitAdapter) hasObjects(ref *GitRef) bool {
+	if _, err := a.repository.CommitObject(ref.Hash); err != nil {
+		return false
+	}
+
+	if !ref.Tag.IsZero() {
+		if _, err := a.repository.TagObject(ref.Tag); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// verify checks the signature of the commit or the tag of the reference against the configured signers.
+func (a *GitAdapter) verify(t *Task[any], ref *GitRef) error {
+	var (
+		signer string
+		err    error
+	)
+
+	switch a.config.VerifySignature {
+	case BEAMER_GIT_VERIFY_SIGNATURE_COMMIT:
+		commit, e := a.repository.CommitObject(ref.Hash)
+		if e != nil {
+			return e
+		}
+
+		signer, err = a.verifier.VerifyCommit(commit)
+	case BEAMER_GIT_VERIFY_SIGNATURE_TAG:
+		if ref.Tag.IsZero() {
+			return fmt.Errorf("Reference is not an annotated tag that can be verified: %s", ref)
+		}
+
+		tag, e := a.repository.TagObject(ref.Tag)
+		if e != nil {
+			return e
+		}
+
+		signer, err = a.verifier.VerifyTag(tag)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("Signature verification failed: %s -> %w", ref, err)
+	}
+
+	t.Log.Infof("Signature is verified: %s by %s", ref, signer)
+
+	return nil
+}
+
+// updateSubmodules recursively initializes and updates the submodules that are in the checked out directories.
+func (a *GitAdapter) updateSubmodules(t *Task[any], w *git.Worktree) error {
+	submodules, err := w.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, submodule := range submodules {
+		path := submodule.Config().Path
+
+		if a.sparseDirectories != nil && !slices.ContainsFunc(a.sparseDirectories, func(dir string) bool {
+			return isInRootDirectory(dir, path)
+		}) {
+			t.Log.Debugf("Submodule is not in the sparse checkout directories: %s", path)
+
+			continue
+		}
+
+		if err := a.updateSubmodule(t, submodule, git.DefaultSubmoduleRecursionDepth); err != nil {
+			return fmt.Errorf("Can not update submodule: %s -> %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// updateSubmodule initializes the submodule and checks out the commit that is recorded in its parent,
+// the fetch is done here instead of go-git, since its submodule update does not pass the TLS options to the transport.
+func (a *GitAdapter) updateSubmodule(t *Task[any], submodule *git.Submodule, depth git.SubmoduleRescursivity) error {
+	t.Log.Debugf("Updating submodule: %s", submodule.Config().Path)
+
+	if err := submodule.Init(); err != nil && !errors.Is(err, git.ErrSubmoduleAlreadyInitialized) {
+		return err
+	}
+
+	status, err := submodule.Status()
+	if err != nil {
+		return err
+	} else if status.Expected.IsZero() {
+		return errors.New("Submodule is not recorded in the index of its parent")
+	}
+
+	r, err := submodule.Repository()
+	if err != nil {
+		return err
+	}
+
+	options := &git.FetchOptions{
+		RemoteName:      git.DefaultRemoteName,
+		Depth:           a.config.Depth,
+		Progress:        t.Log.Writer(),
+		Auth:            a.authMethod,
+		CABundle:        a.caBundle,
+		InsecureSkipTLS: a.config.InsecureSkipTlsVerify,
+		Force:           true,
+	}
+
+	if err := r.Fetch(options); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	// the recorded commit might not be the tip of any branch, so it is fetched directly when the server allows it
+	if _, err := r.CommitObject(status.Expected); err != nil {
+		options.RefSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", status.Expected, status.Expected))}
+
+		if err := r.Fetch(options); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !errors.Is(err, git.ErrExactSHA1NotSupported) {
+			return err
+		}
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{Hash: status.Expected, Force: true}); err != nil {
+		return err
+	}
+
+	if depth == git.NoRecurseSubmodules {
+		return nil
+	}
+
+	submodules, err := w.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range submodules {
+		if err := a.updateSubmodule(t, s, depth-1); err != nil {
+			return fmt.Errorf("Can not update submodule: %s -> %w", s.Config().Path, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveLfs replaces the checked out LFS pointer files of the commit with their content.
+func (a *GitAdapter) resolveLfs(t *Task[any], hash plumbing.Hash) error {
+	commit, err := a.repository.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	pointers := map[string]*GitLfsPointer{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if f.Size > git_lfs_pointer_max_size {
+			return nil
+		}
+
+		path := filepath.Join(a.workingDirectory, f.Name)
+		// files outside of the sparse checkout are not in the working tree
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+
+		if pointer := ParseGitLfsPointer([]byte(content)); pointer != nil {
+			pointers[path] = pointer
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	t.Log.Infof("Resolving LFS objects: %d", len(pointers))
+
+	return a.lfs.Resolve(pointers)
+}
+
+// syncDeletedFiles deletes the files that are removed from the manifest of the working tree since the previous state.
+func (a *GitAdapter) syncDeletedFiles(t *Task[any], m *internal.Mapping, previous *GitAdapterState) error {
+	if previous.Files == nil {
+		t.Log.Warnf("State file does not contain a manifest of the working tree, skipping deleted files.")
+
+		return nil
+	}
+
+	toDelete := []string{}
+	for _, file := range removedFiles(previous.Files, a.state.Files) {
+		if !isInRootDirectory(m.RootDirectory, file) {
+			t.Log.Debugf("File is not in the root directory: %s", file)
+
+			continue
+		}
+
+		toDelete = append(toDelete, file)
+	}
+
+	if len(toDelete) == 0 {
+		t.Log.Infof("No changes found between %s and %s", previous.LastCommit, a.state.LastCommit)
+
+		return nil
+	}
+
+	return syncDelete(t, a.ctx, m, toDelete)
+}
+
+// files returns the manifest of the working tree without the repository internals.
+func (a *GitAdapter) files() ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(a.workingDirectory, func(abs string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		} else if d.Name() == git.GitDirName {
+			// submodules link their repository with a file instead of a directory
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		} else if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(a.workingDirectory, abs)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+
+	return files, err
+}
+
+// isRewritten checks whether the next commit does not descend from the previous commit.
+func (a *GitAdapter) isRewritten(previous plumbing.Hash, next plumbing.Hash) bool {
+	p, err := a.repository.CommitObject(previous)
+	if err != nil {
+		return true
+	}
+
+	n, err := a.repository.CommitObject(next)
+	if err != nil {
+		return true
+	}
+
+	ok, err := p.IsAncestor(n)
+	if err != nil {
+		a.ctx.Log.Debugf("Can not determine whether the history was rewritten: %s -> %s", previous, next)
+
+		return false
+	}
+
+	return !ok
+}
+
+func (a *GitAdapter) logCommit(t *Task[any], prefix string, hash plumbing.Hash) {
+	commit, err := a.repository.CommitObject(hash)
+	if err != nil {
+		t.Log.Warnf("%s: %s", prefix, hash)
+
+		return
+	}
+
+	t.Log.Warnf("%s: %s by %s at %s -> %s", prefix, commit.Hash, commit.Author.String(), commit.Committer.When.Format(time.RFC3339), strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)[0])
+}
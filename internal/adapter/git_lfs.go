@@ -0,0 +1,324 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+const (
+	git_lfs_pointer_version  = "version https://git-lfs.github.com/spec/v1"
+	git_lfs_pointer_max_size = 1024
+	git_lfs_media_type       = "application/vnd.git-lfs+json"
+)
+
+// GitLfsPointer is the content of a pointer file that is committed instead of the large file itself.
+type GitLfsPointer struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type gitLfsBatchRequest struct {
+	Operation string          `json:"operation"`
+	Transfers []string        `json:"transfers"`
+	Objects   []GitLfsPointer `json:"objects"`
+}
+
+type gitLfsBatchResponse struct {
+	Objects []struct {
+		GitLfsPointer
+		Actions struct {
+			Download *struct {
+				Href   string            `json:"href"`
+				Header map[string]string `json:"header"`
+			} `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// gitLfsClient is a minimal client for the batch API of Git LFS that downloads objects with the basic transfer.
+type gitLfsClient struct {
+	client   *http.Client
+	endpoint string
+	auth     transport.AuthMethod
+	cache    string
+}
+
+// ParseGitLfsPointer parses the content of a pointer file, it returns nil when the content is not a pointer.
+func ParseGitLfsPointer(content []byte) *GitLfsPointer {
+	if len(content) > git_lfs_pointer_max_size || !bytes.HasPrefix(content, []byte(git_lfs_pointer_version)) {
+		return nil
+	}
+
+	pointer := &GitLfsPointer{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+
+		switch key {
+		case "oid":
+			pointer.Oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			pointer.Size = size
+		}
+	}
+
+	if len(pointer.Oid) != sha256.Size*2 {
+		return nil
+	}
+
+	return pointer
+}
+
+// GitLfsEndpoint derives the LFS endpoint from the repository URL the same way as the git-lfs client does.
+func GitLfsEndpoint(repository string) (string, error) {
+	endpoint, err := transport.NewEndpoint(repository)
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.TrimSuffix(endpoint.Path, "/")
+	if !strings.HasSuffix(path, ".git") {
+		path = fmt.Sprintf("%s.git", path)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		host := endpoint.Host
+		if endpoint.Port != 0 {
+			host = fmt.Sprintf("%s:%d", host, endpoint.Port)
+		}
+
+		return fmt.Sprintf("%s://%s/%s/info/lfs", endpoint.Protocol, host, strings.TrimPrefix(path, "/")), nil
+	case "ssh":
+		return fmt.Sprintf("https://%s/%s/info/lfs", endpoint.Host, strings.TrimPrefix(path, "/")), nil
+	}
+
+	return "", fmt.Errorf("Can not derive the LFS endpoint from the repository URL, please provide it explicitly: %s", repository)
+}
+
+func newGitLfsClient(endpoint string, auth transport.AuthMethod, caBundle []byte, insecure bool, cache string) *gitLfsClient {
+	//nolint: gosec
+	config := &tls.Config{InsecureSkipVerify: insecure}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pool.AppendCertsFromPEM(caBundle)
+		config.RootCAs = pool
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = config
+
+	return &gitLfsClient{
+		client: &http.Client{
+			Transport: t,
+			Timeout:   30 * time.Minute,
+		},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		auth:     auth,
+		cache:    cache,
+	}
+}
+
+// Resolve replaces the pointer files with their content, objects are cached so that they are only downloaded once.
+func (c *gitLfsClient) Resolve(files map[string]*GitLfsPointer) error {
+	missing := []GitLfsPointer{}
+	seen := map[string]bool{}
+	for _, pointer := range files {
+		if _, err := os.Stat(c.object(pointer.Oid)); err == nil || seen[pointer.Oid] {
+			continue
+		}
+
+		seen[pointer.Oid] = true
+		missing = append(missing, *pointer)
+	}
+
+	if len(missing) > 0 {
+		if err := c.download(missing); err != nil {
+			return err
+		}
+	}
+
+	for path, pointer := range files {
+		if err := c.checkout(pointer, path); err != nil {
+			return fmt.Errorf("Can not check out LFS object: %s -> %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *gitLfsClient) download(objects []GitLfsPointer) error {
+	body, err := json.Marshal(&gitLfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   objects,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/objects/batch", c.endpoint), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", git_lfs_media_type)
+	req.Header.Set("Content-Type", git_lfs_media_type)
+	c.authorize(req)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status from the LFS batch API: %s -> %s", c.endpoint, res.Status)
+	}
+
+	batch := &gitLfsBatchResponse{}
+	if err := json.NewDecoder(res.Body).Decode(batch); err != nil {
+		return fmt.Errorf("Can not parse the response of the LFS batch API: %s -> %w", c.endpoint, err)
+	}
+
+	for _, object := range batch.Objects {
+		if object.Error != nil {
+			return fmt.Errorf("LFS object can not be downloaded: %s -> %d %s", object.Oid, object.Error.Code, object.Error.Message)
+		} else if object.Actions.Download == nil {
+			return fmt.Errorf("LFS object does not have a download action: %s", object.Oid)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, object.Actions.Download.Href, nil)
+		if err != nil {
+			return err
+		}
+		for key, value := range object.Actions.Download.Header {
+			req.Header.Set(key, value)
+		}
+		// the download might point to another host like a presigned URL of an object storage, which must not receive the credentials of the repository
+		if len(object.Actions.Download.Header) == 0 && c.isEndpoint(req.URL) {
+			c.authorize(req)
+		}
+
+		if err := c.fetch(req, object.Oid); err != nil {
+			return fmt.Errorf("Can not download LFS object: %s -> %w", object.Oid, err)
+		}
+	}
+
+	return nil
+}
+
+// fetch downloads the object into the cache while verifying its digest.
+func (c *gitLfsClient) fetch(req *http.Request, oid string) error {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status while downloading: %s", res.Status)
+	}
+
+	path := c.object(oid)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(temp, h), res.Body); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != oid {
+		return fmt.Errorf("Digest of the object does not match: expected %s but got %s", oid, actual)
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// checkout copies the cached object over the pointer file while keeping its permissions.
+func (c *gitLfsClient) checkout(pointer *GitLfsPointer, path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(c.object(pointer.Oid))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dest, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	_, err = io.Copy(dest, src)
+
+	return err
+}
+
+func (c *gitLfsClient) object(oid string) string {
+	return filepath.Join(c.cache, oid[0:2], oid[2:4], oid)
+}
+
+// isEndpoint checks whether the URL has the same scheme and host as the LFS endpoint.
+func (c *gitLfsClient) isEndpoint(u *url.URL) bool {
+	endpoint, err := url.Parse(c.endpoint)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Scheme, endpoint.Scheme) && strings.EqualFold(u.Host, endpoint.Host)
+}
+
+func (c *gitLfsClient) authorize(req *http.Request) {
+	switch auth := c.auth.(type) {
+	case *githttp.BasicAuth:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case *githttp.TokenAuth:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", auth.Token))
+	}
+}
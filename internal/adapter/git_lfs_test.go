@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestGitLfsClientDownloadAuthorization(t *testing.T) {
+	content := []byte("large file")
+	h := sha256.Sum256(content)
+	oid := hex.EncodeToString(h[:])
+
+	// authorization records the header that every server has received for the object
+	authorization := map[string]string{}
+
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization["storage"] = req.Header.Get("Authorization")
+
+		//nolint:errcheck
+		w.Write(content)
+	}))
+	t.Cleanup(storage.Close)
+
+	tests := []struct {
+		name     string
+		external bool
+		expected map[string]string
+	}{
+		{name: "same host", expected: map[string]string{"endpoint": "Bearer token"}},
+		{name: "other host", external: true, expected: map[string]string{"storage": ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clear(authorization)
+
+			var endpoint *httptest.Server
+			endpoint = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/objects/batch" {
+					authorization["endpoint"] = req.Header.Get("Authorization")
+
+					//nolint:errcheck
+					w.Write(content)
+
+					return
+				}
+
+				href := fmt.Sprintf("%s/objects/%s", endpoint.URL, oid)
+				if test.external {
+					href = fmt.Sprintf("%s/presigned/%s", storage.URL, oid)
+				}
+
+				//nolint:errcheck
+				json.NewEncoder(w).Encode(map[string]any{
+					"objects": []map[string]any{
+						{"oid": oid, "size": len(content), "actions": map[string]any{"download": map[string]any{"href": href}}},
+					},
+				})
+			}))
+			t.Cleanup(endpoint.Close)
+
+			cache := t.TempDir()
+			c := newGitLfsClient(endpoint.URL, &githttp.TokenAuth{Token: "token"}, nil, false, cache)
+
+			if err := c.download([]GitLfsPointer{{Oid: oid, Size: int64(len(content))}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for server, expected := range test.expected {
+				if actual, ok := authorization[server]; !ok || actual != expected {
+					t.Fatalf("expected authorization %q on %s, got %q", expected, server, actual)
+				}
+			}
+
+			if _, err := os.Stat(filepath.Join(cache, oid[0:2], oid[2:4], oid)); err != nil {
+				t.Fatalf("expected the object in the cache: %v", err)
+			}
+		})
+	}
+}
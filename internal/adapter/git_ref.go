@@ -0,0 +1,157 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const (
+	git_peeled_suffix = "^{}"
+	// git_pinned_ref keeps the last directly fetched commit, since the fetched objects have to be referenced.
+	git_pinned_ref = "refs/beamer/pinned"
+)
+
+// GitRef is a reference of the remote repository that is resolved to a commit.
+type GitRef struct {
+	// Name is the full name of the resolved reference, it is empty when a commit is pinned directly.
+	Name plumbing.ReferenceName
+	Hash plumbing.Hash
+	// Tag is the hash of the tag object when the reference is an annotated tag.
+	Tag plumbing.Hash
+}
+
+func (r *GitRef) String() string {
+	if r.Name == "" {
+		return r.Hash.String()
+	}
+
+	return fmt.Sprintf("%s@%s", r.Name.Short(), r.Hash)
+}
+
+// RefSpecs returns the refspecs that are required to fetch the reference from the remote.
+func (r *GitRef) RefSpecs() []config.RefSpec {
+	switch {
+	case r.Name == "":
+		// a pinned commit is fetched directly, so that it does not have to be within the depth of any branch
+		return []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:%s", r.Hash, git_pinned_ref)),
+		}
+	case r.Name.IsBranch():
+		return []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:refs/remotes/origin/%s", r.Name, r.Name.Short())),
+		}
+	case !strings.HasPrefix(r.Name.String(), "refs/"):
+		// a HEAD without a symbolic reference is tracked like a branch, since fetching it as is would overwrite the local HEAD
+		return []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:refs/remotes/origin/%s", r.Name, r.Name)),
+		}
+	}
+
+	return []config.RefSpec{
+		config.RefSpec(fmt.Sprintf("+%s:%s", r.Name, r.Name)),
+	}
+}
+
+// ReachableRefSpecs returns the refspecs of all the branches and tags, which is the fallback for fetching a pinned commit
+// when the remote does not allow fetching a commit directly.
+func (r *GitRef) ReachableRefSpecs() []config.RefSpec {
+	return []config.RefSpec{
+		config.RefSpec("+refs/heads/*:refs/remotes/origin/*"),
+		config.RefSpec("+refs/tags/*:refs/tags/*"),
+	}
+}
+
+// ResolveGitRef resolves the given branch, tag, commit or semver constraint against the references advertised by the remote.
+//
+// Branches take precedence over tags with the same name, semver constraints resolve to the highest matching tag.
+func ResolveGitRef(refs []*plumbing.Reference, ref string) (*GitRef, error) {
+	index := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, r := range refs {
+		index[r.Name()] = r
+	}
+
+	resolve := func(name plumbing.ReferenceName) (*GitRef, bool) {
+		r, ok := index[name]
+		if !ok {
+			return nil, false
+		}
+
+		if r.Type() == plumbing.SymbolicReference {
+			target, ok := index[r.Target()]
+			if !ok {
+				return nil, false
+			}
+
+			r = target
+		}
+
+		resolved := &GitRef{Name: r.Name(), Hash: r.Hash()}
+		// annotated tags are advertised with their peeled commit
+		if peeled, ok := index[plumbing.ReferenceName(r.Name().String()+git_peeled_suffix)]; ok {
+			resolved.Tag = r.Hash()
+			resolved.Hash = peeled.Hash()
+		}
+
+		return resolved, true
+	}
+
+	switch {
+	case ref == "" || ref == plumbing.HEAD.String():
+		if resolved, ok := resolve(plumbing.HEAD); ok {
+			return resolved, nil
+		}
+
+		return nil, fmt.Errorf("Remote repository does not advertise a default branch: %s", plumbing.HEAD)
+	case plumbing.IsHash(ref):
+		return &GitRef{Hash: plumbing.NewHash(ref)}, nil
+	case strings.HasPrefix(ref, "refs/"):
+		if resolved, ok := resolve(plumbing.ReferenceName(ref)); ok {
+			return resolved, nil
+		}
+
+		return nil, fmt.Errorf("Reference does not exist in the remote repository: %s", ref)
+	}
+
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)} {
+		if resolved, ok := resolve(name); ok {
+			return resolved, nil
+		}
+	}
+
+	constraint, err := semver.NewConstraint(ref)
+	if err != nil {
+		return nil, fmt.Errorf("Reference is neither a branch, tag, commit nor a valid semver constraint: %s -> %w", ref, err)
+	}
+
+	var (
+		latest *semver.Version
+		name   plumbing.ReferenceName
+	)
+	for _, r := range refs {
+		if !r.Name().IsTag() || strings.HasSuffix(r.Name().String(), git_peeled_suffix) {
+			continue
+		}
+
+		v, err := semver.NewVersion(r.Name().Short())
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+			name = r.Name()
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("No tag in the remote repository matches the semver constraint: %s", ref)
+	}
+
+	resolved, _ := resolve(name)
+
+	return resolved, nil
+}
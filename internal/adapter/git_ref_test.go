@@ -0,0 +1,116 @@
+package adapter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestGitRefRefSpecs(t *testing.T) {
+	hash := plumbing.NewHash("c8f4c22b20595bc9a0d33d46ac2e01804152bd03")
+
+	tests := []struct {
+		name     string
+		ref      *GitRef
+		expected []config.RefSpec
+	}{
+		{
+			name:     "pinned commit is fetched directly",
+			ref:      &GitRef{Hash: hash},
+			expected: []config.RefSpec{"+c8f4c22b20595bc9a0d33d46ac2e01804152bd03:refs/beamer/pinned"},
+		},
+		{
+			name:     "branch",
+			ref:      &GitRef{Name: "refs/heads/main", Hash: hash},
+			expected: []config.RefSpec{"+refs/heads/main:refs/remotes/origin/main"},
+		},
+		{
+			name:     "tag",
+			ref:      &GitRef{Name: "refs/tags/v1.0.0", Hash: hash},
+			expected: []config.RefSpec{"+refs/tags/v1.0.0:refs/tags/v1.0.0"},
+		},
+		{
+			name:     "HEAD without a symbolic reference does not overwrite the local HEAD",
+			ref:      &GitRef{Name: plumbing.HEAD, Hash: hash},
+			expected: []config.RefSpec{"+HEAD:refs/remotes/origin/HEAD"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			specs := test.ref.RefSpecs()
+			if !slices.Equal(specs, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, specs)
+			}
+
+			for _, spec := range specs {
+				if err := spec.Validate(); err != nil {
+					t.Fatalf("expected a valid refspec %s: %v", spec, err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveGitRef(t *testing.T) {
+	hash := plumbing.NewHash("c8f4c22b20595bc9a0d33d46ac2e01804152bd03")
+	peeled := plumbing.NewHash("e8a300b894441d84635d2ccf592c9ad58ae92761")
+
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/v1", peeled),
+		plumbing.NewHashReference("refs/tags/v1", hash),
+		plumbing.NewHashReference("refs/tags/v1.2.0", hash),
+		plumbing.NewHashReference("refs/tags/v1.2.0^{}", peeled),
+		plumbing.NewHashReference("refs/tags/v2.0.0", hash),
+	}
+
+	tests := []struct {
+		name     string
+		refs     []*plumbing.Reference
+		ref      string
+		expected GitRef
+		err      bool
+	}{
+		{
+			name:     "HEAD with a symbolic reference",
+			refs:     append([]*plumbing.Reference{plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main")}, refs...),
+			expected: GitRef{Name: "refs/heads/main", Hash: hash},
+		},
+		{
+			name:     "HEAD without a symbolic reference",
+			refs:     append([]*plumbing.Reference{plumbing.NewHashReference(plumbing.HEAD, hash)}, refs...),
+			ref:      "HEAD",
+			expected: GitRef{Name: plumbing.HEAD, Hash: hash},
+		},
+		{name: "missing HEAD", refs: refs, err: true},
+		{name: "commit", refs: refs, ref: peeled.String(), expected: GitRef{Hash: peeled}},
+		{name: "branch takes precedence over the tag", refs: refs, ref: "v1", expected: GitRef{Name: "refs/heads/v1", Hash: peeled}},
+		{name: "annotated tag is peeled", refs: refs, ref: "v1.2.0", expected: GitRef{Name: "refs/tags/v1.2.0", Hash: peeled, Tag: hash}},
+		{name: "semver constraint", refs: refs, ref: "~1", expected: GitRef{Name: "refs/tags/v1.2.0", Hash: peeled, Tag: hash}},
+		{name: "full reference", refs: refs, ref: "refs/tags/v2.0.0", expected: GitRef{Name: "refs/tags/v2.0.0", Hash: hash}},
+		{name: "missing reference", refs: refs, ref: "refs/heads/missing", err: true},
+		{name: "no matching tag", refs: refs, ref: "^3", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := ResolveGitRef(test.refs, test.ref)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %s", resolved)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if *resolved != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, *resolved)
+			}
+		})
+	}
+}
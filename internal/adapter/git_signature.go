@@ -0,0 +1,306 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	git_ssh_signature_magic     = "SSHSIG"
+	git_ssh_signature_pem_type  = "SSH SIGNATURE"
+	git_ssh_signature_namespace = "git"
+)
+
+type gitAllowedSigner struct {
+	Principals []string
+	Namespaces []string
+	// ValidAfter and ValidBefore limit the time of the signed object that the key is allowed for, zero means unlimited.
+	ValidAfter  time.Time
+	ValidBefore time.Time
+	Key         ssh.PublicKey
+}
+
+// IsValidAt checks whether the time of the signed object is in the validity window of the signer.
+func (s *gitAllowedSigner) IsValidAt(when time.Time) bool {
+	return (s.ValidAfter.IsZero() || !when.Before(s.ValidAfter)) && (s.ValidBefore.IsZero() || !when.After(s.ValidBefore))
+}
+
+// gitSignatureVerifier verifies OpenPGP signatures against a keyring and SSH signatures against allowed signers.
+type gitSignatureVerifier struct {
+	keyring        string
+	allowedSigners []gitAllowedSigner
+}
+
+// parseGitAllowedSigners parses the allowed signers in the format of ssh-keygen, where each line is in the form of
+// "principals [options] key".
+//
+// The options namespaces, valid-after and valid-before are supported, while the certificate authorities are rejected
+// together with any unknown option, since ignoring them would allow more signers than intended.
+func parseGitAllowedSigners(content []byte) ([]gitAllowedSigner, error) {
+	signers := []gitAllowedSigner{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		principals, rest, err := cutGitAllowedSignerPrincipals(line)
+		if err != nil {
+			return nil, err
+		}
+
+		key, _, options, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			return nil, fmt.Errorf("Can not parse the key of the allowed signer: %s -> %w", principals, err)
+		}
+
+		signer := gitAllowedSigner{
+			Principals: strings.Split(principals, ","),
+			Key:        key,
+		}
+
+		for _, option := range options {
+			name, value, _ := strings.Cut(option, "=")
+			value = strings.Trim(value, `"`)
+
+			switch strings.ToLower(name) {
+			case "namespaces":
+				signer.Namespaces = strings.Split(value, ",")
+			case "valid-after":
+				signer.ValidAfter, err = parseGitAllowedSignerTime(value)
+			case "valid-before":
+				signer.ValidBefore, err = parseGitAllowedSignerTime(value)
+			case "cert-authority":
+				return nil, fmt.Errorf("Allowed signer with a certificate authority is not supported: %s", principals)
+			default:
+				return nil, fmt.Errorf("Allowed signer option is not supported: %s -> %s", principals, option)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("Allowed signer option is not valid: %s -> %s: %w", principals, option, err)
+			}
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return signers, scanner.Err()
+}
+
+// cutGitAllowedSignerPrincipals splits the principals from the rest of the line, where quoted principals might contain spaces.
+func cutGitAllowedSignerPrincipals(line string) (string, string, error) {
+	if quoted, ok := strings.CutPrefix(line, `"`); ok {
+		principals, rest, ok := strings.Cut(quoted, `"`)
+		if !ok {
+			return "", "", fmt.Errorf("Principals of the allowed signer are not terminated: %s", line)
+		} else if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			return "", "", fmt.Errorf("Principals of the allowed signer are not separated from the key: %s", line)
+		}
+
+		return principals, strings.TrimLeft(rest, " \t"), nil
+	}
+
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return "", "", fmt.Errorf("Allowed signer does not contain a key: %s", line)
+	}
+
+	return line[:i], strings.TrimLeft(line[i:], " \t"), nil
+}
+
+// parseGitAllowedSignerTime parses the time of the validity options as YYYYMMDD[HHMM[SS]], which is in UTC with a trailing Z
+// and in the local time zone otherwise, like ssh-keygen.
+func parseGitAllowedSignerTime(value string) (time.Time, error) {
+	location := time.Local
+	if v, ok := strings.CutSuffix(value, "Z"); ok {
+		value = v
+		location = time.UTC
+	}
+
+	for _, layout := range []string{"20060102", "200601021504", "20060102150405"} {
+		if len(value) == len(layout) {
+			return time.ParseInLocation(layout, value, location)
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Time should be in the format of YYYYMMDD[HHMM[SS]][Z]: %s", value)
+}
+
+// VerifyCommit verifies the signature of the commit and returns the identity of the signer.
+func (v *gitSignatureVerifier) VerifyCommit(commit *object.Commit) (string, error) {
+	if commit.PGPSignature == "" {
+		return "", fmt.Errorf("Commit is not signed: %s", commit.Hash)
+	}
+
+	if isGitSshSignature(commit.PGPSignature) {
+		payload, err := gitPayload(commit.EncodeWithoutSignature)
+		if err != nil {
+			return "", err
+		}
+
+		return v.verifySsh(commit.PGPSignature, payload, commit.Committer.When)
+	} else if v.keyring == "" {
+		return "", fmt.Errorf("Commit is signed with OpenPGP but no keyring is configured: %s", commit.Hash)
+	}
+
+	entity, err := commit.Verify(v.keyring)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("openpgp:%s", entity.PrimaryKey.KeyIdString()), nil
+}
+
+// VerifyTag verifies the signature of the annotated tag and returns the identity of the signer.
+func (v *gitSignatureVerifier) VerifyTag(tag *object.Tag) (string, error) {
+	if tag.PGPSignature == "" {
+		return "", fmt.Errorf("Tag is not signed: %s", tag.Name)
+	}
+
+	if isGitSshSignature(tag.PGPSignature) {
+		payload, err := gitPayload(tag.EncodeWithoutSignature)
+		if err != nil {
+			return "", err
+		}
+
+		return v.verifySsh(tag.PGPSignature, payload, tag.Tagger.When)
+	} else if v.keyring == "" {
+		return "", fmt.Errorf("Tag is signed with OpenPGP but no keyring is configured: %s", tag.Name)
+	}
+
+	entity, err := tag.Verify(v.keyring)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("openpgp:%s", entity.PrimaryKey.KeyIdString()), nil
+}
+
+// verifySsh verifies a signature in the SSHSIG format and checks whether the key is an allowed signer at the time of the signed object.
+func (v *gitSignatureVerifier) verifySsh(signature string, payload []byte, when time.Time) (string, error) {
+	if len(v.allowedSigners) == 0 {
+		return "", errors.New("Object is signed with SSH but no allowed signers are configured")
+	}
+
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || block.Type != git_ssh_signature_pem_type {
+		return "", errors.New("Can not decode the SSH signature")
+	}
+
+	blob, ok := bytes.CutPrefix(block.Bytes, []byte(git_ssh_signature_magic))
+	if !ok {
+		return "", errors.New("SSH signature does not start with the magic preamble")
+	}
+
+	sig := struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}{}
+	if err := ssh.Unmarshal(blob, &sig); err != nil {
+		return "", fmt.Errorf("Can not parse the SSH signature -> %w", err)
+	}
+
+	if sig.Version != 1 {
+		return "", fmt.Errorf("SSH signature version is not supported: %d", sig.Version)
+	} else if sig.Namespace != git_ssh_signature_namespace {
+		return "", fmt.Errorf("SSH signature is not in the git namespace: %s", sig.Namespace)
+	}
+
+	key, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("SSH signature hash algorithm is not supported: %s", sig.HashAlgorithm)
+	}
+	h.Write(payload)
+
+	signed := append([]byte(git_ssh_signature_magic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+
+	s := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, s); err != nil {
+		return "", fmt.Errorf("Can not parse the SSH signature blob -> %w", err)
+	}
+
+	if err := key.Verify(signed, s); err != nil {
+		return "", fmt.Errorf("SSH signature is not valid -> %w", err)
+	}
+
+	expired := false
+	for _, signer := range v.allowedSigners {
+		if !bytes.Equal(signer.Key.Marshal(), key.Marshal()) {
+			continue
+		} else if len(signer.Namespaces) > 0 && !slices.Contains(signer.Namespaces, git_ssh_signature_namespace) {
+			continue
+		} else if !signer.IsValidAt(when) {
+			expired = true
+
+			continue
+		}
+
+		return fmt.Sprintf("ssh:%s", strings.Join(signer.Principals, ",")), nil
+	}
+
+	if expired {
+		return "", fmt.Errorf("SSH signature is made by a key that is not valid at %s: %s", when.Format(time.RFC3339), ssh.FingerprintSHA256(key))
+	}
+
+	return "", fmt.Errorf("SSH signature is made by a key that is not an allowed signer: %s", ssh.FingerprintSHA256(key))
+}
+
+func isGitSshSignature(signature string) bool {
+	return strings.HasPrefix(strings.TrimSpace(signature), fmt.Sprintf("-----BEGIN %s-----", git_ssh_signature_pem_type))
+}
+
+// gitPayload returns the encoded object without its signature, which is the content that is signed.
+func gitPayload(encode func(plumbing.EncodedObject) error) ([]byte, error) {
+	o := &plumbing.MemoryObject{}
+	if err := encode(o); err != nil {
+		return nil, err
+	}
+
+	r, err := o.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,203 @@
+package adapter
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func testGitSshKey(t *testing.T) (ssh.Signer, string) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+}
+
+// testGitSshSignature signs the payload in the SSHSIG format like ssh-keygen -Y sign.
+func testGitSshSignature(t *testing.T, signer ssh.Signer, payload []byte) string {
+	t.Helper()
+
+	h := sha512.Sum512(payload)
+	signed := append([]byte(git_ssh_signature_magic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{
+		Namespace:     git_ssh_signature_namespace,
+		HashAlgorithm: "sha512",
+		Hash:          h[:],
+	})...)
+
+	sig, err := signer.Sign(rand.Reader, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob := append([]byte(git_ssh_signature_magic), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Signature     []byte
+	}{
+		Version:       1,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     git_ssh_signature_namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	})...)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: git_ssh_signature_pem_type, Bytes: blob}))
+}
+
+func TestParseGitAllowedSigners(t *testing.T) {
+	_, key := testGitSshKey(t)
+
+	tests := []struct {
+		name        string
+		line        string
+		principals  []string
+		namespaces  []string
+		validAfter  time.Time
+		validBefore time.Time
+		err         bool
+	}{
+		{name: "principal", line: fmt.Sprintf("a@example.com %s", key), principals: []string{"a@example.com"}},
+		{name: "principals", line: fmt.Sprintf("a@example.com,b@example.com %s", key), principals: []string{"a@example.com", "b@example.com"}},
+		{name: "tab separated", line: fmt.Sprintf("a@example.com\t%s", key), principals: []string{"a@example.com"}},
+		{name: "quoted principals with spaces", line: fmt.Sprintf(`"a b@example.com,c@example.com" %s`, key), principals: []string{"a b@example.com", "c@example.com"}},
+		{name: "namespaces", line: fmt.Sprintf(`a@example.com namespaces="git,file" %s`, key), principals: []string{"a@example.com"}, namespaces: []string{"git", "file"}},
+		{
+			name:        "validity in utc",
+			line:        fmt.Sprintf(`a@example.com valid-after="20240101Z",valid-before="20241231235959Z" %s`, key),
+			principals:  []string{"a@example.com"},
+			validAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			validBefore: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name:       "validity in local time",
+			line:       fmt.Sprintf(`a@example.com valid-after=202401021530 %s`, key),
+			principals: []string{"a@example.com"},
+			validAfter: time.Date(2024, 1, 2, 15, 30, 0, 0, time.Local),
+		},
+		{name: "invalid validity", line: fmt.Sprintf(`a@example.com valid-after="2024" %s`, key), err: true},
+		{name: "certificate authority", line: fmt.Sprintf(`*@example.com cert-authority %s`, key), err: true},
+		{name: "unknown option", line: fmt.Sprintf(`a@example.com no-touch-required %s`, key), err: true},
+		{name: "unterminated quote", line: fmt.Sprintf(`"a@example.com %s`, key), err: true},
+		{name: "missing key", line: "a@example.com", err: true},
+		{name: "invalid key", line: "a@example.com ssh-ed25519 invalid", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signers, err := parseGitAllowedSigners([]byte(fmt.Sprintf("# comment\n\n%s\n", test.line)))
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %+v", signers)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(signers) != 1 {
+				t.Fatalf("expected a single signer, got %d", len(signers))
+			}
+
+			signer := signers[0]
+			if !slices.Equal(signer.Principals, test.principals) {
+				t.Fatalf("expected principals %v, got %v", test.principals, signer.Principals)
+			}
+
+			if !slices.Equal(signer.Namespaces, test.namespaces) {
+				t.Fatalf("expected namespaces %v, got %v", test.namespaces, signer.Namespaces)
+			}
+
+			if !signer.ValidAfter.Equal(test.validAfter) || !signer.ValidBefore.Equal(test.validBefore) {
+				t.Fatalf("expected validity %s - %s, got %s - %s", test.validAfter, test.validBefore, signer.ValidAfter, signer.ValidBefore)
+			}
+		})
+	}
+}
+
+func TestGitSignatureVerifierSsh(t *testing.T) {
+	signer, key := testGitSshKey(t)
+	_, other := testGitSshKey(t)
+
+	payload := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n")
+	signature := testGitSshSignature(t, signer, payload)
+	signed := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		signers  string
+		when     time.Time
+		identity string
+		err      bool
+	}{
+		{name: "allowed", signers: fmt.Sprintf("a@example.com %s", key), when: signed, identity: "ssh:a@example.com"},
+		{name: "in the validity window", signers: fmt.Sprintf("a@example.com valid-after=20240101Z,valid-before=20241231Z %s", key), when: signed, identity: "ssh:a@example.com"},
+		{name: "not valid yet", signers: fmt.Sprintf("a@example.com valid-after=20240701Z %s", key), when: signed, err: true},
+		{name: "expired", signers: fmt.Sprintf("a@example.com valid-before=20240501Z %s", key), when: signed, err: true},
+		{
+			name:     "renewed key",
+			signers:  fmt.Sprintf("old@example.com valid-before=20240501Z %s\nnew@example.com valid-after=20240501Z %s", key, key),
+			when:     signed,
+			identity: "ssh:new@example.com",
+		},
+		{name: "other namespace", signers: fmt.Sprintf("a@example.com namespaces=file %s", key), when: signed, err: true},
+		{name: "other key", signers: fmt.Sprintf("a@example.com %s", other), when: signed, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signers, err := parseGitAllowedSigners([]byte(test.signers))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := &gitSignatureVerifier{allowedSigners: signers}
+
+			identity, err := v.verifySsh(signature, payload, test.when)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %s", identity)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if identity != test.identity {
+				t.Fatalf("expected identity %s, got %s", test.identity, identity)
+			}
+		})
+	}
+
+	// a modified payload does not match the signature
+	v := &gitSignatureVerifier{allowedSigners: []gitAllowedSigner{{Principals: []string{"a"}, Key: signer.PublicKey()}}}
+	if _, err := v.verifySsh(signature, append(payload, 'x'), signed); err == nil {
+		t.Fatal("expected an error for a modified payload")
+	}
+}
@@ -0,0 +1,432 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+type BeamerHttpArchiveFormat = string
+
+const (
+	BEAMER_HTTP_ARCHIVE_FORMAT_AUTO BeamerHttpArchiveFormat = "auto"
+)
+
+const category_http = "Adapter HTTP"
+
+var httpAdapterFlags = &HttpAdapterConfig{}
+var httpAdapterHeaders = cli.NewStringSlice()
+
+var HttpAdapterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Category:    category_http,
+		Name:        "http-url",
+		Usage:       "URL of the archive to download.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_HTTP_URL"},
+		Destination: &httpAdapterFlags.Url,
+	},
+
+	&cli.StringFlag{
+		Category: category_http,
+		Name:     "http-archive-format",
+		Usage: fmt.Sprintf(
+			"Format of the archive, auto detects it from the URL. enum(%v)",
+			[]string{BEAMER_HTTP_ARCHIVE_FORMAT_AUTO, operations.ARCHIVE_FORMAT_TAR_GZ, operations.ARCHIVE_FORMAT_TAR, operations.ARCHIVE_FORMAT_ZIP},
+		),
+		Required:    false,
+		Value:       BEAMER_HTTP_ARCHIVE_FORMAT_AUTO,
+		EnvVars:     []string{"BEAMER_HTTP_ARCHIVE_FORMAT"},
+		Destination: &httpAdapterFlags.ArchiveFormat,
+	},
+
+	&cli.IntFlag{
+		Category:    category_http,
+		Name:        "http-strip-components",
+		Usage:       "Strip the given number of leading path components while extracting the archive.",
+		Required:    false,
+		Value:       0,
+		EnvVars:     []string{"BEAMER_HTTP_STRIP_COMPONENTS"},
+		Destination: &httpAdapterFlags.StripComponents,
+	},
+
+	&cli.StringFlag{
+		Category:    category_http,
+		Name:        "http-checksum",
+		Usage:       "Checksum to verify the downloaded archive against in the format of algorithm:hex. enum(sha256, sha512)",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_HTTP_CHECKSUM"},
+		Destination: &httpAdapterFlags.Checksum,
+	},
+
+	&cli.StringSliceFlag{
+		Category:    category_http,
+		Name:        "http-headers",
+		Usage:       "Additional headers to send with the request in the format of key: value.",
+		Required:    false,
+		EnvVars:     []string{"BEAMER_HTTP_HEADERS"},
+		Destination: httpAdapterHeaders,
+	},
+
+	&cli.DurationFlag{
+		Category:    category_http,
+		Name:        "http-timeout",
+		Usage:       "Timeout for downloading the archive.",
+		Required:    false,
+		Value:       5 * time.Minute,
+		EnvVars:     []string{"BEAMER_HTTP_TIMEOUT"},
+		Destination: &httpAdapterFlags.Timeout,
+	},
+}
+
+type HttpAdapterConfig struct {
+	Url             string `validate:"required,url"`
+	ArchiveFormat   string `validate:"oneof=auto tar.gz tar zip"`
+	StripComponents int    `validate:"gte=0"`
+	Checksum        string
+	Headers         []string
+	Timeout         time.Duration
+}
+
+type HttpAdapter struct {
+	ctx              *internal.ServiceCtx
+	tl               *TaskList[any]
+	client           *http.Client
+	config           *HttpAdapterConfig
+	workingDirectory string
+	state            *HttpAdapterState
+}
+
+type HttpAdapterState struct {
+	Url          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Digest       string    `json:"digest"`
+	Files        []string  `json:"files"`
+	Time         time.Time `json:"time"`
+}
+
+var _ Adapter = (*HttpAdapter)(nil)
+
+func NewHttpAdapter(p *Plumber, ctx *internal.ServiceCtx) (*HttpAdapter, error) {
+	httpAdapterFlags.Headers = httpAdapterHeaders.Value()
+
+	return newHttpAdapter(p, ctx, httpAdapterFlags)
+}
+
+func newHttpAdapter(p *Plumber, ctx *internal.ServiceCtx, config *HttpAdapterConfig) (*HttpAdapter, error) {
+	adapter := &HttpAdapter{
+		ctx:    ctx,
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+		},
+		workingDirectory: ctx.WorkingDirectory,
+		tl:               (&TaskList[any]{}).New(p),
+	}
+
+	if _, err := url.ParseRequestURI(adapter.config.Url); err != nil {
+		return nil, fmt.Errorf("URL for the HTTP adapter is not valid: %s -> %w", adapter.config.Url, err)
+	}
+
+	for _, header := range adapter.config.Headers {
+		if _, _, ok := strings.Cut(header, ":"); !ok {
+			return nil, fmt.Errorf("Header for the HTTP adapter is not in the format of key: value: %s", header)
+		}
+	}
+
+	return adapter, nil
+}
+
+func (a *HttpAdapter) Init() Job {
+	return a.tl.CreateTask("init").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Downloading archive: %s", a.config.Url)
+
+			// always download initially since the working directory might not be in sync with the persisted state
+			state, err := a.download(t, &HttpAdapterState{})
+			if err != nil {
+				return err
+			}
+
+			a.state = state
+
+			for _, m := range a.ctx.Mappings {
+				previous, err := a.readState(m)
+				if err != nil {
+					return err
+				}
+
+				if previous == nil || previous.Digest != state.Digest {
+					t.Log.Infof("Archive differs from the applied state of mapping %s: %s", m, state.Digest)
+
+					m.State.SetDirty()
+				}
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *HttpAdapter) Sync() Job {
+	return a.tl.CreateTask("sync").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Checking archive: %s", a.config.Url)
+
+			state, err := a.download(t, a.state)
+			if err != nil {
+				return err
+			} else if state == nil {
+				t.Log.Infof("Archive is already up-to-date: %s", a.state.Digest)
+
+				return nil
+			}
+
+			original := a.state.Digest
+			a.state = state
+			a.ctx.SetDirty()
+
+			t.Log.Infof("Archive downloaded successfully: %s -> %s", original, state.Digest)
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *HttpAdapter) Finalize() Job {
+	return a.tl.CreateTask("finalize").
+		Set(func(t *Task[any]) error {
+			for _, m := range a.ctx.Mappings {
+				t.CreateSubtask("sync", "delete", m.Name).
+					ShouldDisable(func(_ *Task[any]) bool {
+						return !(a.ctx.Flags.ForceSync || (m.SyncDelete && m.State.IsDirty()))
+					}).
+					Set(func(t *Task[any]) error {
+						previous, err := a.readState(m)
+						if err != nil {
+							return err
+						} else if previous == nil {
+							t.Log.Warnf("State file does not exists.")
+
+							return nil
+						}
+
+						t.Log.Debugf("Syncing deleted files: from digest %s to %s", previous.Digest, a.state.Digest)
+
+						toDelete := removedFiles(previous.Files, a.state.Files)
+
+						if len(toDelete) == 0 {
+							t.Log.Infof("No changes found between %s and %s", previous.Digest, a.state.Digest)
+
+							return nil
+						}
+
+						return syncDelete(t, a.ctx, m, toDelete)
+					}).
+					AddSelfToTheParentAsSequence()
+
+				t.CreateSubtask("state", "commit", m.Name).
+					Set(func(t *Task[any]) error {
+						m.State.SetClean()
+
+						f, err := json.Marshal(a.state)
+						if err != nil {
+							return err
+						}
+
+						t.Log.Debugf("Writing state file: %s", f)
+
+						return m.State.Write(f)
+					}).
+					AddSelfToTheParentAsSequence()
+			}
+
+			return t.RunSubtasks()
+		}).
+		Job()
+}
+
+func (a *HttpAdapter) Revision() (*Revision, error) {
+	if a.state == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return &Revision{
+		Adapter:  "http",
+		Revision: a.state.Digest,
+		Time:     a.state.Time,
+	}, nil
+}
+
+func (a *HttpAdapter) readState(m *internal.Mapping) (*HttpAdapterState, error) {
+	f, err := m.State.Read()
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return nil, nil
+	}
+
+	state := &HttpAdapterState{}
+	if err := json.Unmarshal(f, state); err != nil {
+		a.ctx.Log.Errorf("Failed to unmarshal state file: %v", err)
+
+		//nolint:nilerr
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// download fetches the archive with a conditional request against the current state and extracts it into the working directory,
+// it returns nil when the archive has not changed.
+func (a *HttpAdapter) download(t *Task[any], current *HttpAdapterState) (*HttpAdapterState, error) {
+	req, err := http.NewRequest(http.MethodGet, a.config.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range a.config.Headers {
+		key, value, _ := strings.Cut(header, ":")
+		req.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	if current.Url == a.config.Url {
+		if current.ETag != "" {
+			req.Header.Set("If-None-Match", current.ETag)
+		}
+		if current.LastModified != "" {
+			req.Header.Set("If-Modified-Since", current.LastModified)
+		}
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return nil, nil
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status while downloading the archive: %s -> %s", a.config.Url, res.Status)
+	}
+
+	temp, err := os.CreateTemp("", "beamer-http-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(temp, digest), res.Body); err != nil {
+		return nil, err
+	}
+
+	if err := temp.Close(); err != nil {
+		return nil, err
+	}
+
+	archive := operations.NewFile(temp.Name())
+
+	if err := a.verifyChecksum(archive); err != nil {
+		return nil, err
+	}
+
+	state := &HttpAdapterState{
+		Url:          a.config.Url,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Digest:       fmt.Sprintf("sha256:%s", hex.EncodeToString(digest.Sum(nil))),
+		Time:         time.Now(),
+	}
+
+	if current.Digest == state.Digest {
+		t.Log.Debugf("Archive content has not changed: %s", state.Digest)
+
+		current.ETag = state.ETag
+		current.LastModified = state.LastModified
+
+		return nil, nil
+	}
+
+	format := a.config.ArchiveFormat
+	if format == BEAMER_HTTP_ARCHIVE_FORMAT_AUTO {
+		format, err = operations.DetectArchiveFormat(res.Request.URL.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t.Log.Debugf("Extracting archive as %s into: %s", format, a.workingDirectory)
+
+	// the archive is extracted next to the working directory, so that a broken archive keeps the previous working tree
+	err = operations.NewFile(a.workingDirectory).ReplaceDir(0755, func(dir string) error {
+		files, err := operations.ExtractArchive(archive, format, dir, a.config.StripComponents)
+		if err != nil {
+			return err
+		}
+		state.Files = files
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Can not extract archive: %s -> %w", a.config.Url, err)
+	}
+
+	return state, nil
+}
+
+func (a *HttpAdapter) verifyChecksum(archive *operations.File) error {
+	if a.config.Checksum == "" {
+		return nil
+	}
+
+	algorithm, expected, ok := strings.Cut(a.config.Checksum, ":")
+	if !ok {
+		return fmt.Errorf("Checksum is not in the format of algorithm:hex: %s", a.config.Checksum)
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("Checksum algorithm is not supported: %s", algorithm)
+	}
+
+	f, err := archive.OpenFile()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("Checksum of the archive does not match: expected %s but got %s", expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,320 @@
+package adapter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const (
+	testHttpETag         = `"v1"`
+	testHttpLastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+)
+
+// testHttpServer serves the archives by their path with an ETag and a Last-Modified header and answers the conditional requests.
+type testHttpServer struct {
+	server   *httptest.Server
+	archives map[string][]byte
+	// conditions records the conditional headers of every request.
+	conditions []string
+	mu         sync.Mutex
+}
+
+func newTestHttpServer(t *testing.T) *testHttpServer {
+	t.Helper()
+
+	s := &testHttpServer{
+		archives: map[string][]byte{},
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.conditions = append(s.conditions, fmt.Sprintf("%s|%s", req.Header.Get("If-None-Match"), req.Header.Get("If-Modified-Since")))
+
+		content, ok := s.archives[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("ETag", testHttpETag)
+		w.Header().Set("Last-Modified", testHttpLastModified)
+
+		if req.Header.Get("If-None-Match") == testHttpETag {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		//nolint:errcheck
+		w.Write(content)
+	}))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+func (s *testHttpServer) adapter(t *testing.T, path string, config *HttpAdapterConfig) *HttpAdapter {
+	t.Helper()
+
+	if config == nil {
+		config = &HttpAdapterConfig{}
+	}
+
+	config.Url = s.server.URL + path
+	if config.ArchiveFormat == "" {
+		config.ArchiveFormat = BEAMER_HTTP_ARCHIVE_FORMAT_AUTO
+	}
+
+	return &HttpAdapter{
+		client:           s.server.Client(),
+		config:           config,
+		workingDirectory: filepath.Join(t.TempDir(), "working"),
+	}
+}
+
+func testHttpTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testHttpZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testHttpChecksum(content []byte) string {
+	h := sha256.Sum256(content)
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(h[:]))
+}
+
+func TestHttpAdapterDownload(t *testing.T) {
+	s := newTestHttpServer(t)
+
+	files := map[string]string{"release/conf/app.conf": "app", "release/README": "readme"}
+	s.archives["/config.tar.gz"] = testOciTarGz(t, files)
+	s.archives["/config.tar"] = testHttpTar(t, files)
+	s.archives["/config.zip"] = testHttpZip(t, files)
+	s.archives["/config"] = testHttpZip(t, files)
+	s.archives["/traversal.tar"] = testHttpTar(t, map[string]string{"../outside": "outside"})
+	s.archives["/traversal.zip"] = testHttpZip(t, map[string]string{"a/../../outside": "outside"})
+	s.archives["/broken.tar.gz"] = []byte("not an archive")
+
+	tests := []struct {
+		name     string
+		path     string
+		config   *HttpAdapterConfig
+		expected map[string]string
+		err      bool
+	}{
+		{name: "tar.gz", path: "/config.tar.gz", expected: map[string]string{"release/conf/app.conf": "app", "release/README": "readme"}},
+		{name: "tar", path: "/config.tar", expected: map[string]string{"release/conf/app.conf": "app"}},
+		{name: "zip", path: "/config.zip", expected: map[string]string{"release/conf/app.conf": "app"}},
+		{name: "explicit format", path: "/config", config: &HttpAdapterConfig{ArchiveFormat: "zip"}, expected: map[string]string{"release/README": "readme"}},
+		{name: "undetectable format", path: "/config", err: true},
+		{name: "strip components", path: "/config.tar.gz", config: &HttpAdapterConfig{StripComponents: 1}, expected: map[string]string{"conf/app.conf": "app", "README": "readme"}},
+		{name: "checksum", path: "/config.tar", config: &HttpAdapterConfig{Checksum: testHttpChecksum(s.archives["/config.tar"])}, expected: map[string]string{"release/README": "readme"}},
+		{name: "checksum mismatch", path: "/config.tar", config: &HttpAdapterConfig{Checksum: testHttpChecksum([]byte("other"))}, err: true},
+		{name: "checksum algorithm", path: "/config.tar", config: &HttpAdapterConfig{Checksum: "md5:00"}, err: true},
+		{name: "path traversal in tar", path: "/traversal.tar", err: true},
+		{name: "path traversal in zip", path: "/traversal.zip", err: true},
+		{name: "broken archive", path: "/broken.tar.gz", err: true},
+		{name: "missing", path: "/missing.tar", err: true},
+	}
+
+	task := &Task[any]{Log: logrus.NewEntry(logrus.New())}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := s.adapter(t, test.path, test.config)
+
+			state, err := a.download(task, &HttpAdapterState{})
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %+v", state)
+				}
+
+				// nothing is left behind next to the working directory
+				entries, err := os.ReadDir(filepath.Dir(a.workingDirectory))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(entries) != 0 {
+					t.Fatalf("expected nothing to be extracted, got %d entries", len(entries))
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if state.Digest != testHttpChecksum(s.archives[test.path]) {
+				t.Fatalf("expected the digest of the archive, got %s", state.Digest)
+			}
+
+			for path, expected := range test.expected {
+				content, err := os.ReadFile(filepath.Join(a.workingDirectory, path))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if string(content) != expected {
+					t.Fatalf("expected %q in %s, got %q", expected, path, content)
+				}
+			}
+		})
+	}
+}
+
+func TestHttpAdapterConditionalRequest(t *testing.T) {
+	s := newTestHttpServer(t)
+	s.archives["/config.tar"] = testHttpTar(t, map[string]string{"app.conf": "v1"})
+
+	a := s.adapter(t, "/config.tar", nil)
+	task := &Task[any]{Log: logrus.NewEntry(logrus.New())}
+
+	state, err := a.download(task, &HttpAdapterState{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if state.ETag != testHttpETag || state.LastModified != testHttpLastModified {
+		t.Fatalf("expected the validators of the response, got %q and %q", state.ETag, state.LastModified)
+	}
+
+	// the validators of the state are sent back, where the server answers with not modified
+	if next, err := a.download(task, state); err != nil || next != nil {
+		t.Fatalf("expected no changes for a not modified response, got %v -> %v", next, err)
+	}
+
+	if expected := fmt.Sprintf("%s|%s", testHttpETag, testHttpLastModified); s.conditions[1] != expected {
+		t.Fatalf("expected the conditional headers %q, got %q", expected, s.conditions[1])
+	}
+
+	// the validators are not sent for another URL, since they belong to the previous one
+	other := *state
+	other.Url = s.server.URL + "/other.tar"
+
+	s.archives["/config.tar"] = testHttpTar(t, map[string]string{"app.conf": "v2"})
+
+	next, err := a.download(task, &other)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.conditions[2] != "|" {
+		t.Fatalf("expected no conditional headers for another URL, got %q", s.conditions[2])
+	}
+
+	if next == nil || next.Digest == state.Digest {
+		t.Fatalf("expected a new revision, got %+v", next)
+	}
+
+	// the same content without validators is detected by its digest and keeps the previous working tree
+	next.ETag = ""
+	next.LastModified = ""
+
+	if unchanged, err := a.download(task, next); err != nil || unchanged != nil {
+		t.Fatalf("expected no changes for the same digest, got %v -> %v", unchanged, err)
+	}
+
+	if next.ETag != testHttpETag {
+		t.Fatalf("expected the validators to be refreshed on the current state, got %q", next.ETag)
+	}
+
+	content, err := os.ReadFile(filepath.Join(a.workingDirectory, "app.conf"))
+	if err != nil || string(content) != "v2" {
+		t.Fatalf("expected the working tree of the new revision, got %q -> %v", content, err)
+	}
+}
+
+func TestHttpAdapterKeepsWorkingTreeOnFailure(t *testing.T) {
+	s := newTestHttpServer(t)
+	s.archives["/config.tar"] = testHttpTar(t, map[string]string{"app.conf": "v1"})
+
+	a := s.adapter(t, "/config.tar", nil)
+	task := &Task[any]{Log: logrus.NewEntry(logrus.New())}
+
+	state, err := a.download(task, &HttpAdapterState{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.archives["/config.tar"] = testHttpTar(t, map[string]string{"../outside": "v2"})
+
+	if _, err := a.download(task, &HttpAdapterState{Digest: state.Digest}); err == nil {
+		t.Fatal("expected an error for the broken archive")
+	}
+
+	content, err := os.ReadFile(filepath.Join(a.workingDirectory, "app.conf"))
+	if err != nil || string(content) != "v1" {
+		t.Fatalf("expected the previous working tree to be kept, got %q -> %v", content, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(a.workingDirectory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected only the working directory to remain, got %d entries", len(entries))
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(a.workingDirectory), "outside")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written outside of the working directory: %v", err)
+	}
+}
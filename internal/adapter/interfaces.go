@@ -1,11 +1,30 @@
 package adapter
 
 import (
+	"errors"
+	"time"
+
 	. "gitlab.kilic.dev/libraries/plumber/v5"
 )
 
+// ErrNotInitialized is returned for the revision before the source is initialized.
+var ErrNotInitialized = errors.New("Adapter is not initialized yet")
+
 type Adapter interface {
 	Init() Job
 	Sync() Job
 	Finalize() Job
+	Revision() (*Revision, error)
+}
+
+type Revision struct {
+	Adapter  string    `json:"adapter,omitempty"`
+	Revision string    `json:"revision,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Branch   string    `json:"branch,omitempty"`
+	Time     time.Time `json:"time,omitzero"`
+	Author   string    `json:"author,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	// Layers are the revisions of the layers in order, when the source is combined from multiple layers.
+	Layers []*Revision `json:"layers,omitempty"`
 }
@@ -0,0 +1,343 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const (
+	LAYERS_DIRECTORY        = "layers"
+	LAYERS_MERGED_DIRECTORY = "merged"
+)
+
+// LayerDefinition is a source that is layered over the previous ones, where the options are named after the flags of the adapter.
+type LayerDefinition struct {
+	Name    string
+	Adapter string
+	Options map[string][]string
+}
+
+type layer struct {
+	name             string
+	adapter          Adapter
+	workingDirectory string
+}
+
+// LayeredAdapter combines the working trees of multiple adapters in order, where the later layers override the files of the earlier ones.
+type LayeredAdapter struct {
+	ctx              *internal.ServiceCtx
+	tl               *TaskList[any]
+	layers           []*layer
+	workingDirectory string
+	// providers are the names of the layers that provide the files relative to the working directory.
+	providers map[string]string
+	conflicts []string
+	composed  string
+}
+
+var _ Adapter = (*LayeredAdapter)(nil)
+
+func NewLayeredAdapter(p *Plumber, ctx *internal.ServiceCtx, definitions []LayerDefinition) (*LayeredAdapter, error) {
+	adapter := &LayeredAdapter{
+		ctx:              ctx,
+		tl:               (&TaskList[any]{}).New(p),
+		workingDirectory: filepath.Join(ctx.WorkingDirectory, LAYERS_MERGED_DIRECTORY),
+		providers:        map[string]string{},
+	}
+
+	for _, definition := range definitions {
+		workingDirectory := filepath.Join(ctx.WorkingDirectory, LAYERS_DIRECTORY, definition.Name)
+
+		lctx := ctx.Layer(definition.Name, workingDirectory)
+		lctx.Provides = adapter.provides
+
+		a, err := newLayerAdapter(p, lctx, definition)
+		if err != nil {
+			return nil, fmt.Errorf("Can not create the layer: %s -> %w", definition.Name, err)
+		}
+
+		ctx.Log.Infof("Using layer with %s adapter: %s", definition.Adapter, definition.Name)
+
+		adapter.layers = append(adapter.layers, &layer{
+			name:             definition.Name,
+			adapter:          a,
+			workingDirectory: workingDirectory,
+		})
+	}
+
+	return adapter, nil
+}
+
+// WorkingDirectory returns the directory that the layers are combined into.
+func (a *LayeredAdapter) WorkingDirectory() string {
+	return a.workingDirectory
+}
+
+func (a *LayeredAdapter) Init() Job {
+	return a.tl.CreateTask("layers", "init").
+		Set(func(t *Task[any]) error {
+			jobs := []Job{}
+			for _, l := range a.layers {
+				jobs = append(jobs, l.adapter.Init())
+			}
+
+			if err := a.tl.RunJobs(a.tl.JobSequence(jobs...)); err != nil {
+				return err
+			}
+
+			return a.compose(t)
+		}).
+		Job()
+}
+
+func (a *LayeredAdapter) Sync() Job {
+	return a.tl.CreateTask("layers", "sync").
+		Set(func(t *Task[any]) error {
+			jobs := []Job{}
+			for _, l := range a.layers {
+				jobs = append(jobs, l.adapter.Sync())
+			}
+
+			if err := a.tl.RunJobs(a.tl.JobSequence(jobs...)); err != nil {
+				return err
+			}
+
+			if err := a.compose(t); err != nil {
+				return err
+			}
+
+			for _, conflict := range a.conflicts {
+				a.ctx.Changes.Add(internal.Change{
+					Action: internal.CHANGE_ACTION_CONFLICT,
+					Path:   conflict,
+				})
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *LayeredAdapter) Finalize() Job {
+	return a.tl.CreateTask("layers", "finalize").
+		Set(func(t *Task[any]) error {
+			jobs := []Job{}
+			for _, l := range a.layers {
+				jobs = append(jobs, l.adapter.Finalize())
+			}
+
+			if err := a.tl.RunJobs(a.tl.JobSequence(jobs...)); err != nil {
+				return err
+			}
+
+			// the history and the pin of the combined revision are kept by the mappings themselves
+			for _, m := range a.ctx.Mappings {
+				m.State.SetClean()
+
+				if err := m.State.Flush(); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+// Revision combines the revisions of the layers, which changes whenever any of the layers changes.
+func (a *LayeredAdapter) Revision() (*Revision, error) {
+	revision := &Revision{
+		Adapter: "layers",
+		Layers:  []*Revision{},
+	}
+
+	digest := sha256.New()
+	for _, l := range a.layers {
+		r, err := l.adapter.Revision()
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(digest, "%s:%s:%s\n", l.name, r.Adapter, r.Revision)
+
+		if r.Time.After(revision.Time) {
+			revision.Time = r.Time
+		}
+
+		revision.Layers = append(revision.Layers, r)
+	}
+	revision.Revision = hex.EncodeToString(digest.Sum(nil))
+
+	return revision, nil
+}
+
+// compose combines the working trees of the layers into the working directory, when any of the layers has changed since the last time.
+func (a *LayeredAdapter) compose(t *Task[any]) error {
+	revision, err := a.Revision()
+	if err != nil {
+		return err
+	} else if revision.Revision == a.composed {
+		return nil
+	}
+
+	t.Log.Infof("Combining layers into the working directory: %s", a.workingDirectory)
+
+	if err := os.RemoveAll(a.workingDirectory); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(a.workingDirectory, 0755); err != nil {
+		return err
+	}
+
+	providers := map[string]string{}
+	conflicts := []string{}
+
+	for _, l := range a.layers {
+		err := filepath.WalkDir(l.workingDirectory, func(abs string, d fs.DirEntry, e error) error {
+			if e != nil {
+				return fmt.Errorf("Error walking: %s -> %w", abs, e)
+			} else if abs == l.workingDirectory {
+				return nil
+			}
+
+			// the metadata of the repositories is not a part of the source
+			if d.Name() == git.GitDirName {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			rel, err := filepath.Rel(l.workingDirectory, abs)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(a.workingDirectory, rel)
+
+			stat, err := os.Lstat(abs)
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if _, ok := providers[rel]; ok {
+					return fmt.Errorf("Layer provides a directory where a previous layer provides a file: %s -> %s", l.name, rel)
+				}
+
+				return os.MkdirAll(target, stat.Mode().Perm())
+			}
+
+			if previous, ok := providers[rel]; ok {
+				t.Log.Warnf("File is provided by multiple layers, %s overrides %s: %s", l.name, previous, rel)
+
+				conflicts = append(conflicts, rel)
+
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			} else if info, err := os.Lstat(target); err == nil && info.IsDir() {
+				return fmt.Errorf("Layer provides a file where a previous layer provides a directory: %s -> %s", l.name, rel)
+			}
+
+			providers[rel] = l.name
+
+			if stat.Mode()&os.ModeSymlink != 0 {
+				link, err := os.Readlink(abs)
+				if err != nil {
+					return err
+				}
+
+				return os.Symlink(link, target)
+			}
+
+			// the working trees are only replaced by the adapters, so the files can be shared instead of copied
+			if err := os.Link(abs, target); err == nil {
+				return nil
+			}
+
+			return operations.NewFile(abs).CopyTo(operations.NewFile(target))
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	slices.Sort(conflicts)
+	conflicts = slices.Compact(conflicts)
+
+	a.providers = providers
+	a.conflicts = conflicts
+	a.composed = revision.Revision
+
+	t.Log.Debugf("Layers are combined with %d files and %d conflicts: %s", len(providers), len(conflicts), revision.Revision)
+
+	return nil
+}
+
+// provides checks whether any of the layers still provides the file relative to the working directory.
+func (a *LayeredAdapter) provides(file string) bool {
+	_, ok := a.providers[filepath.Clean(strings.TrimPrefix(file, "/"))]
+
+	return ok
+}
+
+// newLayerAdapter creates the adapter of a layer, where the options override the flags of the adapter.
+func newLayerAdapter(p *Plumber, ctx *internal.ServiceCtx, definition LayerDefinition) (Adapter, error) {
+	switch definition.Adapter {
+	case "git":
+		gitAdapterFlags.SparseCheckoutPaths = gitAdapterSparseCheckoutPaths.Value()
+
+		config := *gitAdapterFlags
+		if err := applyOptions(&config, definition.Adapter, definition.Options); err != nil {
+			return nil, err
+		}
+
+		return newGitAdapter(p, ctx, &config)
+	case "http":
+		httpAdapterFlags.Headers = httpAdapterHeaders.Value()
+
+		config := *httpAdapterFlags
+		if err := applyOptions(&config, definition.Adapter, definition.Options); err != nil {
+			return nil, err
+		}
+
+		return newHttpAdapter(p, ctx, &config)
+	case "local":
+		config := *localAdapterFlags
+		if err := applyOptions(&config, definition.Adapter, definition.Options); err != nil {
+			return nil, err
+		}
+
+		return newLocalAdapter(p, ctx, &config)
+	case "oci":
+		config := *ociAdapterFlags
+		if err := applyOptions(&config, definition.Adapter, definition.Options); err != nil {
+			return nil, err
+		}
+
+		return newOciAdapter(p, ctx, &config)
+	case "s3":
+		config := *s3AdapterFlags
+		if err := applyOptions(&config, definition.Adapter, definition.Options); err != nil {
+			return nil, err
+		}
+
+		return newS3Adapter(p, ctx, &config)
+	default:
+		return nil, fmt.Errorf("Adapter %s is not supported", definition.Adapter)
+	}
+}
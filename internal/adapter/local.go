@@ -0,0 +1,264 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const category_local = "Adapter Local"
+
+var localAdapterFlags = &LocalAdapterConfig{}
+
+var LocalAdapterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Category:    category_local,
+		Name:        "local-directory",
+		Usage:       "Directory to use as the source, like a mounted volume or a config map.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_LOCAL_DIRECTORY"},
+		Destination: &localAdapterFlags.Directory,
+	},
+}
+
+type LocalAdapterConfig struct {
+	Directory string `validate:"required,dir"`
+}
+
+type LocalAdapter struct {
+	ctx              *internal.ServiceCtx
+	tl               *TaskList[any]
+	config           *LocalAdapterConfig
+	workingDirectory string
+	state            *LocalAdapterState
+}
+
+type LocalAdapterState struct {
+	Digest   string              `json:"digest"`
+	Manifest operations.Manifest `json:"manifest"`
+	Time     time.Time           `json:"time"`
+}
+
+var _ Adapter = (*LocalAdapter)(nil)
+
+func NewLocalAdapter(p *Plumber, ctx *internal.ServiceCtx) (*LocalAdapter, error) {
+	return newLocalAdapter(p, ctx, localAdapterFlags)
+}
+
+func newLocalAdapter(p *Plumber, ctx *internal.ServiceCtx, config *LocalAdapterConfig) (*LocalAdapter, error) {
+	adapter := &LocalAdapter{
+		ctx:              ctx,
+		config:           config,
+		workingDirectory: ctx.WorkingDirectory,
+		tl:               (&TaskList[any]{}).New(p),
+	}
+
+	if f := operations.NewFile(adapter.config.Directory); !f.IsDir() {
+		return nil, fmt.Errorf("Source directory for the local adapter does not exist: %s", f.Abs())
+	}
+
+	if err := validateWorkingDirectory(ctx, adapter.workingDirectory, adapter.config.Directory); err != nil {
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
+func (a *LocalAdapter) Init() Job {
+	return a.tl.CreateTask("init").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Copying directory: %s", a.config.Directory)
+
+			manifest, err := operations.NewManifest(a.config.Directory)
+			if err != nil {
+				return err
+			}
+
+			// always start from a clean copy since the working directory might not be in sync with the persisted state
+			if err := os.RemoveAll(a.workingDirectory); err != nil {
+				return err
+			}
+
+			if err := a.copy(t, operations.Manifest{}, manifest); err != nil {
+				return err
+			}
+
+			a.state = newLocalAdapterState(manifest)
+
+			for _, m := range a.ctx.Mappings {
+				previous, err := a.readState(m)
+				if err != nil {
+					return err
+				}
+
+				if previous == nil || !previous.Manifest.Equal(manifest) {
+					t.Log.Infof("Directory differs from the applied state of mapping %s: %s", m, a.state.Digest)
+
+					m.State.SetDirty()
+				}
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *LocalAdapter) Sync() Job {
+	return a.tl.CreateTask("sync").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Checking directory: %s", a.config.Directory)
+
+			manifest, err := operations.NewManifest(a.config.Directory)
+			if err != nil {
+				return err
+			}
+
+			if a.state.Manifest.Equal(manifest) {
+				t.Log.Infof("Directory is already up-to-date: %s", a.state.Digest)
+
+				return nil
+			}
+
+			if err := a.copy(t, a.state.Manifest, manifest); err != nil {
+				return err
+			}
+
+			original := a.state.Digest
+			a.state = newLocalAdapterState(manifest)
+			a.ctx.SetDirty()
+
+			t.Log.Infof("Directory copied successfully: %s -> %s", original, a.state.Digest)
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *LocalAdapter) Finalize() Job {
+	return a.tl.CreateTask("finalize").
+		Set(func(t *Task[any]) error {
+			for _, m := range a.ctx.Mappings {
+				t.CreateSubtask("sync", "delete", m.Name).
+					ShouldDisable(func(_ *Task[any]) bool {
+						return !(a.ctx.Flags.ForceSync || (m.SyncDelete && m.State.IsDirty()))
+					}).
+					Set(func(t *Task[any]) error {
+						previous, err := a.readState(m)
+						if err != nil {
+							return err
+						} else if previous == nil {
+							t.Log.Warnf("State file does not exists.")
+
+							return nil
+						}
+
+						t.Log.Debugf("Syncing deleted files: from digest %s to %s", previous.Digest, a.state.Digest)
+
+						toDelete := previous.Manifest.Removed(a.state.Manifest)
+
+						if len(toDelete) == 0 {
+							t.Log.Infof("No changes found between %s and %s", previous.Digest, a.state.Digest)
+
+							return nil
+						}
+
+						return syncDelete(t, a.ctx, m, toDelete)
+					}).
+					AddSelfToTheParentAsSequence()
+
+				t.CreateSubtask("state", "commit", m.Name).
+					Set(func(t *Task[any]) error {
+						m.State.SetClean()
+
+						f, err := json.Marshal(a.state)
+						if err != nil {
+							return err
+						}
+
+						t.Log.Debugf("Writing state file: %s", a.state.Digest)
+
+						return m.State.Write(f)
+					}).
+					AddSelfToTheParentAsSequence()
+			}
+
+			return t.RunSubtasks()
+		}).
+		Job()
+}
+
+func (a *LocalAdapter) Revision() (*Revision, error) {
+	if a.state == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return &Revision{
+		Adapter:  "local",
+		Revision: a.state.Digest,
+		Time:     a.state.Time,
+	}, nil
+}
+
+func (a *LocalAdapter) readState(m *internal.Mapping) (*LocalAdapterState, error) {
+	f, err := m.State.Read()
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return nil, nil
+	}
+
+	state := &LocalAdapterState{}
+	if err := json.Unmarshal(f, state); err != nil {
+		a.ctx.Log.Errorf("Failed to unmarshal state file: %v", err)
+
+		//nolint:nilerr
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// copy brings the working directory from the previous manifest to the next one.
+func (a *LocalAdapter) copy(t *Task[any], previous operations.Manifest, next operations.Manifest) error {
+	for _, path := range previous.Changed(next) {
+		sf := operations.NewFile(a.config.Directory, path)
+		tf := operations.NewFile(a.workingDirectory, path)
+
+		t.Log.Debugf("Copying file: %s -> %s", sf.Abs(), tf.Abs())
+
+		if err := operations.NewFile(tf.Cwd()).Mkdirp(0755); err != nil {
+			return err
+		}
+
+		if err := sf.CopyTo(tf); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range previous.Removed(next) {
+		tf := operations.NewFile(a.workingDirectory, path)
+
+		t.Log.Debugf("Removing file: %s", tf.Abs())
+
+		if err := tf.Remove(); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newLocalAdapterState(manifest operations.Manifest) *LocalAdapterState {
+	return &LocalAdapterState{
+		Digest:   manifest.Digest(),
+		Manifest: manifest,
+		Time:     time.Now(),
+	}
+}
@@ -0,0 +1,418 @@
+package adapter
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const category_oci = "Adapter OCI"
+
+var ociAdapterFlags = &OciAdapterConfig{}
+
+var OciAdapterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Category:    category_oci,
+		Name:        "oci-reference",
+		Usage:       "Reference of the artifact to pull in the format of registry/repository:tag or registry/repository@digest.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_OCI_REFERENCE"},
+		Destination: &ociAdapterFlags.Reference,
+	},
+
+	&cli.StringFlag{
+		Category:    category_oci,
+		Name:        "oci-username",
+		Usage:       "Username for authenticating against the registry.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_OCI_USERNAME"},
+		Destination: &ociAdapterFlags.Username,
+	},
+
+	&cli.StringFlag{
+		Category:    category_oci,
+		Name:        "oci-password",
+		Usage:       "Password for authenticating against the registry.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_OCI_PASSWORD"},
+		Destination: &ociAdapterFlags.Password,
+	},
+
+	&cli.StringFlag{
+		Category:    category_oci,
+		Name:        "oci-token",
+		Usage:       "Bearer token for authenticating against the registry.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_OCI_TOKEN"},
+		Destination: &ociAdapterFlags.Token,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_oci,
+		Name:        "oci-plain-http",
+		Usage:       "Use plain HTTP instead of HTTPS to connect to the registry.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_OCI_PLAIN_HTTP"},
+		Destination: &ociAdapterFlags.PlainHttp,
+	},
+
+	&cli.DurationFlag{
+		Category:    category_oci,
+		Name:        "oci-timeout",
+		Usage:       "Timeout for the requests against the registry.",
+		Required:    false,
+		Value:       5 * time.Minute,
+		EnvVars:     []string{"BEAMER_OCI_TIMEOUT"},
+		Destination: &ociAdapterFlags.Timeout,
+	},
+}
+
+type OciAdapterConfig struct {
+	Reference string `validate:"required"`
+	Username  string
+	Password  string `validate:"required_with=Username"`
+	Token     string
+	PlainHttp bool
+	Timeout   time.Duration
+}
+
+type OciAdapter struct {
+	ctx              *internal.ServiceCtx
+	tl               *TaskList[any]
+	registry         *ociRegistry
+	config           *OciAdapterConfig
+	workingDirectory string
+	state            *OciAdapterState
+}
+
+type OciAdapterState struct {
+	Reference string    `json:"reference"`
+	Digest    string    `json:"digest"`
+	Files     []string  `json:"files"`
+	Time      time.Time `json:"time"`
+}
+
+var _ Adapter = (*OciAdapter)(nil)
+
+func NewOciAdapter(p *Plumber, ctx *internal.ServiceCtx) (*OciAdapter, error) {
+	return newOciAdapter(p, ctx, ociAdapterFlags)
+}
+
+func newOciAdapter(p *Plumber, ctx *internal.ServiceCtx, config *OciAdapterConfig) (*OciAdapter, error) {
+	log := ctx.Log.WithField(LOG_FIELD_CONTEXT, "adapter").WithField(LOG_FIELD_STATUS, "oci")
+
+	reference, err := ParseOciReference(config.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := &OciAdapter{
+		ctx:    ctx,
+		config: config,
+		registry: &ociRegistry{
+			client: &http.Client{
+				Timeout: config.Timeout,
+			},
+			scheme:    "https",
+			reference: reference,
+			username:  config.Username,
+			password:  config.Password,
+			token:     config.Token,
+		},
+		workingDirectory: ctx.WorkingDirectory,
+		tl:               (&TaskList[any]{}).New(p),
+	}
+
+	if config.PlainHttp {
+		adapter.registry.scheme = "http"
+
+		log.Warnln("Using plain HTTP to connect to the registry.")
+	}
+
+	switch {
+	case config.Token != "":
+		log.Infof("Using bearer token authentication for the registry.")
+	case config.Username != "":
+		log.Infof("Using basic authentication for the registry.")
+	}
+
+	return adapter, nil
+}
+
+func (a *OciAdapter) Init() Job {
+	return a.tl.CreateTask("init").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Pulling artifact: %s", a.registry.reference)
+
+			// always pull initially since the working directory might not be in sync with the persisted state
+			state, err := a.pull(t, &OciAdapterState{})
+			if err != nil {
+				return err
+			}
+
+			a.state = state
+
+			for _, m := range a.ctx.Mappings {
+				previous, err := a.readState(m)
+				if err != nil {
+					return err
+				}
+
+				if previous == nil || previous.Digest != state.Digest {
+					t.Log.Infof("Artifact differs from the applied state of mapping %s: %s", m, state.Digest)
+
+					m.State.SetDirty()
+				}
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *OciAdapter) Sync() Job {
+	return a.tl.CreateTask("sync").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Checking artifact: %s", a.registry.reference)
+
+			state, err := a.pull(t, a.state)
+			if err != nil {
+				return err
+			} else if state == nil {
+				t.Log.Infof("Artifact is already up-to-date: %s", a.state.Digest)
+
+				return nil
+			}
+
+			original := a.state.Digest
+			a.state = state
+			a.ctx.SetDirty()
+
+			t.Log.Infof("Artifact pulled successfully: %s -> %s", original, state.Digest)
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *OciAdapter) Finalize() Job {
+	return a.tl.CreateTask("finalize").
+		Set(func(t *Task[any]) error {
+			for _, m := range a.ctx.Mappings {
+				t.CreateSubtask("sync", "delete", m.Name).
+					ShouldDisable(func(_ *Task[any]) bool {
+						return !(a.ctx.Flags.ForceSync || (m.SyncDelete && m.State.IsDirty()))
+					}).
+					Set(func(t *Task[any]) error {
+						previous, err := a.readState(m)
+						if err != nil {
+							return err
+						} else if previous == nil {
+							t.Log.Warnf("State file does not exists.")
+
+							return nil
+						}
+
+						t.Log.Debugf("Syncing deleted files: from digest %s to %s", previous.Digest, a.state.Digest)
+
+						toDelete := removedFiles(previous.Files, a.state.Files)
+
+						if len(toDelete) == 0 {
+							t.Log.Infof("No changes found between %s and %s", previous.Digest, a.state.Digest)
+
+							return nil
+						}
+
+						return syncDelete(t, a.ctx, m, toDelete)
+					}).
+					AddSelfToTheParentAsSequence()
+
+				t.CreateSubtask("state", "commit", m.Name).
+					Set(func(t *Task[any]) error {
+						m.State.SetClean()
+
+						f, err := json.Marshal(a.state)
+						if err != nil {
+							return err
+						}
+
+						t.Log.Debugf("Writing state file: %s", f)
+
+						return m.State.Write(f)
+					}).
+					AddSelfToTheParentAsSequence()
+			}
+
+			return t.RunSubtasks()
+		}).
+		Job()
+}
+
+func (a *OciAdapter) Revision() (*Revision, error) {
+	if a.state == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return &Revision{
+		Adapter:  "oci",
+		Revision: a.state.Digest,
+		Branch:   a.registry.reference.Tag,
+		Time:     a.state.Time,
+	}, nil
+}
+
+func (a *OciAdapter) readState(m *internal.Mapping) (*OciAdapterState, error) {
+	f, err := m.State.Read()
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return nil, nil
+	}
+
+	state := &OciAdapterState{}
+	if err := json.Unmarshal(f, state); err != nil {
+		a.ctx.Log.Errorf("Failed to unmarshal state file: %v", err)
+
+		//nolint:nilerr
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// pull resolves the manifest of the artifact and extracts its layers into the working directory,
+// it returns nil when the resolved digest matches the current state.
+func (a *OciAdapter) pull(t *Task[any], current *OciAdapterState) (*OciAdapterState, error) {
+	manifest, digest, err := a.registry.Manifest(a.registry.reference.Ref())
+	if err != nil {
+		return nil, err
+	}
+
+	// an index is resolved to its first manifest, since configuration artifacts are platform independent
+	if manifest.MediaType == OCI_MEDIA_TYPE_IMAGE_INDEX || manifest.MediaType == OCI_MEDIA_TYPE_DOCKER_LIST {
+		if len(manifest.Manifests) == 0 {
+			return nil, fmt.Errorf("Index of the artifact does not contain any manifests: %s", a.registry.reference)
+		}
+
+		t.Log.Debugf("Resolving index to the manifest: %s -> %s", digest, manifest.Manifests[0].Digest)
+
+		manifest, _, err = a.registry.Manifest(manifest.Manifests[0].Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if current.Digest == digest {
+		return nil, nil
+	}
+
+	t.Log.Debugf("Extracting %d layers into: %s", len(manifest.Layers), a.workingDirectory)
+
+	state := &OciAdapterState{
+		Reference: a.registry.reference.String(),
+		Digest:    digest,
+		Files:     []string{},
+		Time:      time.Now(),
+	}
+
+	// the layers are extracted next to the working directory, so that a broken layer keeps the previous working tree
+	err = operations.NewFile(a.workingDirectory).ReplaceDir(0755, func(dir string) error {
+		for _, layer := range manifest.Layers {
+			files, err := a.extractLayer(t, layer, dir)
+			if err != nil {
+				return fmt.Errorf("Can not extract layer: %s -> %w", layer.Digest, err)
+			}
+
+			state.Files = append(state.Files, files...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (a *OciAdapter) extractLayer(t *Task[any], layer OciDescriptor, dir string) ([]string, error) {
+	temp, err := os.CreateTemp("", "beamer-oci-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if err := a.registry.Blob(layer.Digest, temp); err != nil {
+		return nil, err
+	}
+
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(layer.MediaType, "tar+gzip"), strings.HasSuffix(layer.MediaType, "tar.gzip"):
+		t.Log.Debugf("Extracting layer as a compressed archive: %s", layer.Digest)
+
+		gz, err := gzip.NewReader(temp)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		return operations.ExtractTar(gz, dir, 0)
+	case strings.HasSuffix(layer.MediaType, ".tar"), strings.HasSuffix(layer.MediaType, "tar"):
+		t.Log.Debugf("Extracting layer as an archive: %s", layer.Digest)
+
+		return operations.ExtractTar(temp, dir, 0)
+	}
+
+	title, ok := layer.Annotations[OCI_ANNOTATION_TITLE]
+	if !ok {
+		t.Log.Warnf("Skipping layer without a title annotation: %s with %s", layer.Digest, layer.MediaType)
+
+		return nil, nil
+	}
+
+	path := filepath.Join(dir, filepath.Join("/", title))
+
+	t.Log.Debugf("Writing layer as a file: %s -> %s", layer.Digest, path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, temp); err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{rel}, nil
+}
@@ -0,0 +1,333 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	OCI_MEDIA_TYPE_IMAGE_MANIFEST  = "application/vnd.oci.image.manifest.v1+json"
+	OCI_MEDIA_TYPE_IMAGE_INDEX     = "application/vnd.oci.image.index.v1+json"
+	OCI_MEDIA_TYPE_DOCKER_MANIFEST = "application/vnd.docker.distribution.manifest.v2+json"
+	OCI_MEDIA_TYPE_DOCKER_LIST     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	OCI_ANNOTATION_TITLE = "org.opencontainers.image.title"
+
+	oci_default_registry = "registry-1.docker.io"
+)
+
+type OciReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+type OciDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type OciManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []OciDescriptor `json:"manifests,omitempty"`
+	Layers    []OciDescriptor `json:"layers,omitempty"`
+}
+
+// ociRegistry is a minimal client for the OCI distribution API that is able to pull manifests and blobs.
+type ociRegistry struct {
+	client    *http.Client
+	scheme    string
+	reference *OciReference
+	username  string
+	password  string
+	token     string
+	mu        sync.Mutex
+}
+
+// ParseOciReference parses a reference in the format of registry/repository:tag or registry/repository@digest.
+func ParseOciReference(ref string) (*OciReference, error) {
+	reference := &OciReference{}
+
+	name := ref
+	if before, digest, ok := strings.Cut(name, "@"); ok {
+		name = before
+		reference.Digest = digest
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		reference.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	registry, repository, ok := strings.Cut(name, "/")
+	if !ok || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry = oci_default_registry
+		repository = name
+
+		if !strings.Contains(repository, "/") {
+			repository = fmt.Sprintf("library/%s", repository)
+		}
+	}
+
+	if repository == "" {
+		return nil, fmt.Errorf("OCI reference does not contain a repository: %s", ref)
+	}
+
+	reference.Registry = registry
+	reference.Repository = repository
+
+	if reference.Tag == "" && reference.Digest == "" {
+		reference.Tag = "latest"
+	}
+
+	return reference, nil
+}
+
+func (r *OciReference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Digest)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
+
+// Ref returns the tag or the digest of the reference, where the digest takes precedence.
+func (r *OciReference) Ref() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// Manifest fetches the manifest for the given tag or digest and returns it with its digest.
+func (r *ociRegistry) Manifest(ref string) (*OciManifest, string, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url("manifests", ref), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", strings.Join([]string{OCI_MEDIA_TYPE_IMAGE_MANIFEST, OCI_MEDIA_TYPE_IMAGE_INDEX, OCI_MEDIA_TYPE_DOCKER_MANIFEST, OCI_MEDIA_TYPE_DOCKER_LIST}, ", "))
+
+	res, err := r.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("Unexpected status while fetching the manifest: %s -> %s", ref, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	h := sha256.Sum256(body)
+	digest := fmt.Sprintf("sha256:%s", hex.EncodeToString(h[:]))
+
+	if strings.HasPrefix(ref, "sha256:") && ref != digest {
+		return nil, "", fmt.Errorf("Digest of the manifest does not match: expected %s but got %s", ref, digest)
+	}
+
+	manifest := &OciManifest{}
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, "", fmt.Errorf("Can not parse the manifest: %s -> %w", ref, err)
+	}
+
+	if manifest.MediaType == "" {
+		manifest.MediaType = res.Header.Get("Content-Type")
+	}
+
+	return manifest, digest, nil
+}
+
+// Blob fetches the blob with the given digest and writes it into the writer while verifying its digest.
+func (r *ociRegistry) Blob(digest string, w io.Writer) error {
+	algorithm, expected, ok := strings.Cut(digest, ":")
+	if !ok || algorithm != "sha256" {
+		return fmt.Errorf("Digest algorithm is not supported: %s", digest)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.url("blobs", digest), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status while fetching the blob: %s -> %s", digest, res.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), res.Body); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return fmt.Errorf("Digest of the blob does not match: expected %s but got sha256:%s", digest, actual)
+	}
+
+	return nil
+}
+
+func (r *ociRegistry) url(kind string, ref string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", r.scheme, r.reference.Registry, r.reference.Repository, kind, ref)
+}
+
+// do sends the request and handles the authentication challenge of the registry once.
+func (r *ociRegistry) do(req *http.Request) (*http.Response, error) {
+	r.authorize(req)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	} else if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	challenge := res.Header.Get("WWW-Authenticate")
+	res.Body.Close()
+
+	if err := r.authenticate(challenge); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	r.authorize(retry)
+
+	return r.client.Do(retry)
+}
+
+func (r *ociRegistry) authorize(req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.token))
+	} else if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+}
+
+// authenticate resolves the challenge of the registry, where bearer challenges are exchanged for a token.
+func (r *ociRegistry) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.username == "" {
+			return errors.New("Registry requires basic authentication but no credentials are configured")
+		}
+
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("Registry requires an unsupported authentication scheme: %s", challenge)
+	}
+
+	values := parseOciChallenge(params)
+	realm, ok := values["realm"]
+	if !ok {
+		return fmt.Errorf("Registry authentication challenge does not contain a realm: %s", challenge)
+	}
+
+	u, err := url.Parse(realm)
+	if err != nil {
+		return err
+	}
+
+	query := u.Query()
+	if service, ok := values["service"]; ok {
+		query.Set("service", service)
+	}
+	if scope, ok := values["scope"]; ok {
+		query.Set("scope", scope)
+	} else {
+		query.Set("scope", fmt.Sprintf("repository:%s:pull", r.reference.Repository))
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status while fetching the registry token: %s -> %s", u.Host, res.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+
+	if r.token == "" {
+		return errors.New("Registry did not return a token")
+	}
+
+	return nil
+}
+
+func parseOciChallenge(params string) map[string]string {
+	values := map[string]string{}
+
+	for params != "" {
+		var param string
+		key, rest, ok := strings.Cut(strings.TrimLeft(params, ", "), "=")
+		if !ok {
+			break
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			param = rest[1 : end+1]
+			params = rest[end+2:]
+		} else {
+			param, params, _ = strings.Cut(rest, ",")
+		}
+
+		values[strings.ToLower(strings.TrimSpace(key))] = param
+	}
+
+	return values
+}
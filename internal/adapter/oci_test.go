@@ -0,0 +1,370 @@
+package adapter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const (
+	testOciRepository = "configs/app"
+	testOciUsername   = "user"
+	testOciPassword   = "password"
+	testOciToken      = "token"
+)
+
+// testOciRegistry is an in-process registry that serves the manifests and the blobs of a single repository behind a bearer challenge.
+type testOciRegistry struct {
+	server    *httptest.Server
+	manifests map[string][]byte
+	types     map[string]string
+	blobs     map[string][]byte
+	tokens    atomic.Int32
+}
+
+func newTestOciRegistry(t *testing.T) *testOciRegistry {
+	t.Helper()
+
+	r := &testOciRegistry{
+		manifests: map[string][]byte{},
+		types:     map[string]string{},
+		blobs:     map[string][]byte{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if user, password, ok := req.BasicAuth(); !ok || user != testOciUsername || password != testOciPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if req.URL.Query().Get("scope") != fmt.Sprintf("repository:%s:pull", testOciRepository) {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		r.tokens.Add(1)
+
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]string{"token": testOciToken})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != fmt.Sprintf("Bearer %s", testOciToken) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:%s:pull"`, r.server.URL, testOciRepository))
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		path, ok := strings.CutPrefix(req.URL.Path, fmt.Sprintf("/v2/%s/", testOciRepository))
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		kind, ref, _ := strings.Cut(path, "/")
+
+		var content []byte
+		switch kind {
+		case "manifests":
+			content, ok = r.manifests[ref]
+			w.Header().Set("Content-Type", r.types[ref])
+		case "blobs":
+			content, ok = r.blobs[ref]
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		//nolint:errcheck
+		w.Write(content)
+	})
+
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+func (r *testOciRegistry) client(t *testing.T, ref string) *ociRegistry {
+	t.Helper()
+
+	reference, err := ParseOciReference(fmt.Sprintf("%s/%s%s", strings.TrimPrefix(r.server.URL, "http://"), testOciRepository, ref))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &ociRegistry{
+		client:    r.server.Client(),
+		scheme:    "http",
+		reference: reference,
+		username:  testOciUsername,
+		password:  testOciPassword,
+	}
+}
+
+func (r *testOciRegistry) addBlob(content []byte) string {
+	digest := testOciDigest(content)
+	r.blobs[digest] = content
+
+	return digest
+}
+
+func (r *testOciRegistry) addManifest(t *testing.T, tag string, manifest *OciManifest) string {
+	t.Helper()
+
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := testOciDigest(content)
+	for _, ref := range []string{tag, digest} {
+		if ref == "" {
+			continue
+		}
+
+		r.manifests[ref] = content
+		r.types[ref] = manifest.MediaType
+	}
+
+	return digest
+}
+
+func testOciDigest(content []byte) string {
+	h := sha256.Sum256(content)
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(h[:]))
+}
+
+func testOciTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestOciRegistryManifest(t *testing.T) {
+	r := newTestOciRegistry(t)
+	digest := r.addManifest(t, "v1", &OciManifest{MediaType: OCI_MEDIA_TYPE_IMAGE_MANIFEST})
+
+	r.manifests["sha256:0000000000000000000000000000000000000000000000000000000000000000"] = r.manifests["v1"]
+
+	tests := []struct {
+		name string
+		ref  string
+		err  bool
+	}{
+		{name: "tag", ref: "v1"},
+		{name: "digest", ref: digest},
+		{name: "digest mismatch", ref: "sha256:0000000000000000000000000000000000000000000000000000000000000000", err: true},
+		{name: "missing", ref: "v2", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest, actual, err := r.client(t, ":v1").Manifest(test.ref)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %s", actual)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if actual != digest {
+				t.Fatalf("expected digest %s, got %s", digest, actual)
+			}
+
+			if manifest.MediaType != OCI_MEDIA_TYPE_IMAGE_MANIFEST {
+				t.Fatalf("expected media type %s, got %s", OCI_MEDIA_TYPE_IMAGE_MANIFEST, manifest.MediaType)
+			}
+		})
+	}
+}
+
+func TestOciRegistryBearerChallenge(t *testing.T) {
+	r := newTestOciRegistry(t)
+	r.addManifest(t, "v1", &OciManifest{MediaType: OCI_MEDIA_TYPE_IMAGE_MANIFEST})
+
+	client := r.client(t, ":v1")
+	for range 3 {
+		if _, _, err := client.Manifest("v1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the token is reused for the following requests
+	if tokens := r.tokens.Load(); tokens != 1 {
+		t.Fatalf("expected a single token request, got %d", tokens)
+	}
+
+	client = r.client(t, ":v1")
+	client.password = "wrong"
+
+	if _, _, err := client.Manifest("v1"); err == nil {
+		t.Fatal("expected an error with wrong credentials")
+	}
+}
+
+func TestOciRegistryBlob(t *testing.T) {
+	r := newTestOciRegistry(t)
+	digest := r.addBlob([]byte("content"))
+
+	corrupt := testOciDigest([]byte("expected"))
+	r.blobs[corrupt] = []byte("corrupt")
+
+	tests := []struct {
+		name     string
+		digest   string
+		expected string
+		err      bool
+	}{
+		{name: "blob", digest: digest, expected: "content"},
+		{name: "digest mismatch", digest: corrupt, err: true},
+		{name: "unsupported algorithm", digest: "sha512:00", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			err := r.client(t, ":v1").Blob(test.digest, buf)
+			if test.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if buf.String() != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestOciAdapterPull(t *testing.T) {
+	r := newTestOciRegistry(t)
+
+	archive := r.addBlob(testOciTarGz(t, map[string]string{"conf/app.conf": "archive"}))
+	file := r.addBlob([]byte("file"))
+
+	manifest := r.addManifest(t, "", &OciManifest{
+		MediaType: OCI_MEDIA_TYPE_IMAGE_MANIFEST,
+		Layers: []OciDescriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: archive},
+			{MediaType: "application/vnd.oci.image.layer.v1", Digest: file, Annotations: map[string]string{OCI_ANNOTATION_TITLE: "file.txt"}},
+		},
+	})
+	index := r.addManifest(t, "v1", &OciManifest{
+		MediaType: OCI_MEDIA_TYPE_IMAGE_INDEX,
+		Manifests: []OciDescriptor{{MediaType: OCI_MEDIA_TYPE_IMAGE_MANIFEST, Digest: manifest}},
+	})
+
+	corrupt := testOciDigest([]byte("expected"))
+	r.blobs[corrupt] = []byte("corrupt")
+	r.addManifest(t, "broken", &OciManifest{
+		MediaType: OCI_MEDIA_TYPE_IMAGE_MANIFEST,
+		Layers:    []OciDescriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar", Digest: corrupt}},
+	})
+
+	task := &Task[any]{Log: logrus.NewEntry(logrus.New())}
+
+	for _, ref := range []string{":v1", "@" + index} {
+		t.Run(ref, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "working")
+			a := &OciAdapter{registry: r.client(t, ref), workingDirectory: dir}
+
+			state, err := a.pull(task, &OciAdapterState{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the digest of the index identifies the revision, since it is the one that the reference points to
+			if state.Digest != index {
+				t.Fatalf("expected digest %s, got %s", index, state.Digest)
+			}
+
+			for path, expected := range map[string]string{"conf/app.conf": "archive", "file.txt": "file"} {
+				content, err := os.ReadFile(filepath.Join(dir, path))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if string(content) != expected {
+					t.Fatalf("expected %q in %s, got %q", expected, path, content)
+				}
+			}
+
+			if state, err := a.pull(task, state); err != nil || state != nil {
+				t.Fatalf("expected no changes for the same digest, got %v -> %v", state, err)
+			}
+
+			// a broken layer keeps the previous working tree
+			a.registry = r.client(t, ":broken")
+			if _, err := a.pull(task, state); err == nil {
+				t.Fatal("expected an error for the broken layer")
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "file.txt")); err != nil {
+				t.Fatalf("expected the previous working tree to be kept: %v", err)
+			}
+
+			entries, err := os.ReadDir(filepath.Dir(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("expected only the working directory to remain, got %d entries", len(entries))
+			}
+		})
+	}
+}
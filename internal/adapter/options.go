@@ -0,0 +1,60 @@
+package adapter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyOptions sets the fields of the adapter configuration from the options that are named after the flags of the adapter,
+// e.g. git-ssh-private-key sets the SshPrivateKey field of the Git adapter, where the repeated options fill the lists.
+func applyOptions(config any, prefix string, options map[string][]string) error {
+	value := reflect.ValueOf(config).Elem()
+
+	for key, values := range options {
+		name, ok := strings.CutPrefix(key, prefix+"-")
+		if !ok {
+			return fmt.Errorf("Option is not supported by the %s adapter: %s", prefix, key)
+		}
+
+		field := value.FieldByNameFunc(func(field string) bool {
+			return strings.EqualFold(field, strings.ReplaceAll(name, "-", ""))
+		})
+		if !field.IsValid() || !field.CanSet() || len(values) == 0 {
+			return fmt.Errorf("Option is not supported by the %s adapter: %s", prefix, key)
+		}
+
+		last := values[len(values)-1]
+
+		var err error
+
+		switch {
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			field.Set(reflect.ValueOf(append([]string{}, values...)))
+		case field.Kind() == reflect.String:
+			field.SetString(last)
+		case field.Kind() == reflect.Bool:
+			var parsed bool
+			parsed, err = strconv.ParseBool(last)
+			field.SetBool(parsed)
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			var parsed time.Duration
+			parsed, err = time.ParseDuration(last)
+			field.SetInt(int64(parsed))
+		case field.Kind() == reflect.Int:
+			var parsed int
+			parsed, err = strconv.Atoi(last)
+			field.SetInt(int64(parsed))
+		default:
+			return fmt.Errorf("Option can not be set for the %s adapter: %s", prefix, key)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Option is not valid for the %s adapter: %s -> %w", prefix, key, err)
+		}
+	}
+
+	return nil
+}
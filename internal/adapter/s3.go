@@ -0,0 +1,467 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const category_s3 = "Adapter S3"
+
+var s3AdapterFlags = &S3AdapterConfig{}
+
+var S3AdapterFlags = []cli.Flag{
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-endpoint",
+		Usage:       "Endpoint of the object storage, defaults to AWS S3 in the given region.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_ENDPOINT"},
+		Destination: &s3AdapterFlags.Endpoint,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-region",
+		Usage:       "Region of the bucket.",
+		Required:    false,
+		Value:       "us-east-1",
+		EnvVars:     []string{"BEAMER_S3_REGION", "AWS_REGION"},
+		Destination: &s3AdapterFlags.Region,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-bucket",
+		Usage:       "Bucket to download the objects from.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_BUCKET"},
+		Destination: &s3AdapterFlags.Bucket,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-prefix",
+		Usage:       "Prefix of the objects to download as a directory, which is stripped from the paths in the working directory.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_PREFIX"},
+		Destination: &s3AdapterFlags.Prefix,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-access-key-id",
+		Usage:       "Access key id for authenticating against the object storage, anonymous access is used when empty.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID"},
+		Destination: &s3AdapterFlags.AccessKeyId,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-secret-access-key",
+		Usage:       "Secret access key for authenticating against the object storage.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY"},
+		Destination: &s3AdapterFlags.SecretAccessKey,
+	},
+
+	&cli.StringFlag{
+		Category:    category_s3,
+		Name:        "s3-session-token",
+		Usage:       "Session token for authenticating with temporary credentials.",
+		Required:    false,
+		Value:       "",
+		EnvVars:     []string{"BEAMER_S3_SESSION_TOKEN", "AWS_SESSION_TOKEN"},
+		Destination: &s3AdapterFlags.SessionToken,
+	},
+
+	&cli.BoolFlag{
+		Category:    category_s3,
+		Name:        "s3-path-style",
+		Usage:       "Use path-style addressing instead of virtual-hosted style, which is required by most of the self-hosted object storages.",
+		Required:    false,
+		Value:       false,
+		EnvVars:     []string{"BEAMER_S3_PATH_STYLE"},
+		Destination: &s3AdapterFlags.PathStyle,
+	},
+
+	&cli.DurationFlag{
+		Category:    category_s3,
+		Name:        "s3-timeout",
+		Usage:       "Timeout for the requests against the object storage.",
+		Required:    false,
+		Value:       5 * time.Minute,
+		EnvVars:     []string{"BEAMER_S3_TIMEOUT"},
+		Destination: &s3AdapterFlags.Timeout,
+	},
+}
+
+type S3AdapterConfig struct {
+	Endpoint        string
+	Region          string `validate:"required"`
+	Bucket          string `validate:"required"`
+	Prefix          string
+	AccessKeyId     string
+	SecretAccessKey string `validate:"required_with=AccessKeyId"`
+	SessionToken    string
+	PathStyle       bool
+	Timeout         time.Duration
+}
+
+type S3Adapter struct {
+	ctx    *internal.ServiceCtx
+	tl     *TaskList[any]
+	client *s3Client
+	config *S3AdapterConfig
+	// prefix is the prefix of the objects as a directory, so that it does not match the siblings with the same beginning.
+	prefix           string
+	workingDirectory string
+	state            *S3AdapterState
+}
+
+type S3AdapterState struct {
+	Bucket  string            `json:"bucket"`
+	Prefix  string            `json:"prefix"`
+	Digest  string            `json:"digest"`
+	Objects map[string]string `json:"objects"`
+	Time    time.Time         `json:"time"`
+}
+
+var _ Adapter = (*S3Adapter)(nil)
+
+func NewS3Adapter(p *Plumber, ctx *internal.ServiceCtx) (*S3Adapter, error) {
+	return newS3Adapter(p, ctx, s3AdapterFlags)
+}
+
+func newS3Adapter(p *Plumber, ctx *internal.ServiceCtx, config *S3AdapterConfig) (*S3Adapter, error) {
+	log := ctx.Log.WithField(LOG_FIELD_CONTEXT, "adapter").WithField(LOG_FIELD_STATUS, "s3")
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Endpoint for the S3 adapter is not valid: %s -> %w", endpoint, err)
+	} else if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Endpoint for the S3 adapter should contain the scheme and the host: %s", endpoint)
+	}
+
+	adapter := &S3Adapter{
+		ctx:    ctx,
+		config: config,
+		client: &s3Client{
+			client: &http.Client{
+				Timeout: config.Timeout,
+			},
+			endpoint:        u,
+			region:          config.Region,
+			bucket:          config.Bucket,
+			pathStyle:       config.PathStyle,
+			accessKeyId:     config.AccessKeyId,
+			secretAccessKey: config.SecretAccessKey,
+			sessionToken:    config.SessionToken,
+		},
+		prefix:           s3Prefix(config.Prefix),
+		workingDirectory: ctx.WorkingDirectory,
+		tl:               (&TaskList[any]{}).New(p),
+	}
+
+	if err := validateWorkingDirectory(ctx, adapter.workingDirectory); err != nil {
+		return nil, err
+	}
+
+	if config.AccessKeyId == "" {
+		log.Infof("Using anonymous access for the object storage.")
+	} else {
+		log.Infof("Using access key authentication for the object storage.")
+	}
+
+	return adapter, nil
+}
+
+func (a *S3Adapter) Init() Job {
+	return a.tl.CreateTask("init").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Downloading objects: %s/%s", a.config.Bucket, a.config.Prefix)
+
+			// always start from a clean copy since the working directory might not be in sync with the persisted state
+			if err := os.RemoveAll(a.workingDirectory); err != nil {
+				return err
+			}
+
+			state, err := a.download(t, &S3AdapterState{Objects: map[string]string{}})
+			if err != nil {
+				return err
+			}
+
+			a.state = state
+
+			for _, m := range a.ctx.Mappings {
+				previous, err := a.readState(m)
+				if err != nil {
+					return err
+				}
+
+				if previous == nil || previous.Digest != state.Digest {
+					t.Log.Infof("Objects differ from the applied state of mapping %s: %s", m, state.Digest)
+
+					m.State.SetDirty()
+				}
+			}
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *S3Adapter) Sync() Job {
+	return a.tl.CreateTask("sync").
+		Set(func(t *Task[any]) error {
+			t.Log.Infof("Checking objects: %s/%s", a.config.Bucket, a.config.Prefix)
+
+			state, err := a.download(t, a.state)
+			if err != nil {
+				return err
+			}
+
+			if state.Digest == a.state.Digest {
+				t.Log.Infof("Objects are already up-to-date: %s", a.state.Digest)
+
+				return nil
+			}
+
+			original := a.state.Digest
+			a.state = state
+			a.ctx.SetDirty()
+
+			t.Log.Infof("Objects downloaded successfully: %s -> %s", original, state.Digest)
+
+			return nil
+		}).
+		Job()
+}
+
+func (a *S3Adapter) Finalize() Job {
+	return a.tl.CreateTask("finalize").
+		Set(func(t *Task[any]) error {
+			for _, m := range a.ctx.Mappings {
+				t.CreateSubtask("sync", "delete", m.Name).
+					ShouldDisable(func(_ *Task[any]) bool {
+						return !(a.ctx.Flags.ForceSync || (m.SyncDelete && m.State.IsDirty()))
+					}).
+					Set(func(t *Task[any]) error {
+						previous, err := a.readState(m)
+						if err != nil {
+							return err
+						} else if previous == nil {
+							t.Log.Warnf("State file does not exists.")
+
+							return nil
+						}
+
+						t.Log.Debugf("Syncing deleted files: from digest %s to %s", previous.Digest, a.state.Digest)
+
+						toDelete := []string{}
+						for path := range previous.Objects {
+							if _, ok := a.state.Objects[path]; !ok {
+								t.Log.Debugf("Object was in the old listing but does not exists in the new listing: %s", path)
+								toDelete = append(toDelete, path)
+							}
+						}
+
+						if len(toDelete) == 0 {
+							t.Log.Infof("No changes found between %s and %s", previous.Digest, a.state.Digest)
+
+							return nil
+						}
+
+						return syncDelete(t, a.ctx, m, toDelete)
+					}).
+					AddSelfToTheParentAsSequence()
+
+				t.CreateSubtask("state", "commit", m.Name).
+					Set(func(t *Task[any]) error {
+						m.State.SetClean()
+
+						f, err := json.Marshal(a.state)
+						if err != nil {
+							return err
+						}
+
+						t.Log.Debugf("Writing state file: %s", a.state.Digest)
+
+						return m.State.Write(f)
+					}).
+					AddSelfToTheParentAsSequence()
+			}
+
+			return t.RunSubtasks()
+		}).
+		Job()
+}
+
+func (a *S3Adapter) Revision() (*Revision, error) {
+	if a.state == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return &Revision{
+		Adapter:  "s3",
+		Revision: a.state.Digest,
+		Time:     a.state.Time,
+	}, nil
+}
+
+func (a *S3Adapter) readState(m *internal.Mapping) (*S3AdapterState, error) {
+	f, err := m.State.Read()
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return nil, nil
+	}
+
+	state := &S3AdapterState{}
+	if err := json.Unmarshal(f, state); err != nil {
+		a.ctx.Log.Errorf("Failed to unmarshal state file: %v", err)
+
+		//nolint:nilerr
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// download lists the objects and only fetches the ones with a different ETag than the current state,
+// while removing the objects from the working directory that do not exist anymore.
+func (a *S3Adapter) download(t *Task[any], current *S3AdapterState) (*S3AdapterState, error) {
+	objects, err := a.client.List(a.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &S3AdapterState{
+		Bucket:  a.config.Bucket,
+		Prefix:  a.config.Prefix,
+		Objects: map[string]string{},
+		Time:    current.Time,
+	}
+
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+
+		path, ok := strings.CutPrefix(object.Key, a.prefix)
+		if !ok || path == "" {
+			continue
+		}
+
+		tf := operations.NewFile(a.workingDirectory, filepath.Join("/", path))
+		state.Objects[path] = object.ETag
+
+		if etag, ok := current.Objects[path]; ok && etag == object.ETag && tf.Exists() {
+			continue
+		}
+
+		t.Log.Debugf("Downloading object: %s -> %s", object.Key, tf.Abs())
+
+		if err := a.get(object.Key, tf); err != nil {
+			return nil, err
+		}
+	}
+
+	for path := range current.Objects {
+		if _, ok := state.Objects[path]; ok {
+			continue
+		}
+
+		tf := operations.NewFile(a.workingDirectory, filepath.Join("/", path))
+
+		t.Log.Debugf("Removing object: %s", tf.Abs())
+
+		if err := tf.Remove(); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	state.Digest = s3Digest(state.Objects)
+	if state.Digest != current.Digest {
+		state.Time = time.Now()
+	}
+
+	return state, nil
+}
+
+func (a *S3Adapter) get(key string, tf *operations.File) error {
+	if err := operations.NewFile(tf.Cwd()).Mkdirp(0755); err != nil {
+		return err
+	}
+
+	// download into a temporary file first, so that a failed download does not leave a partial file behind
+	temp, err := os.CreateTemp(tf.Cwd(), fmt.Sprintf(".%s-", tf.Rel()))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if err := a.client.Get(key, temp); err != nil {
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), tf.Abs())
+}
+
+// s3Prefix normalizes the prefix to a directory, where an empty prefix stands for the whole bucket.
+func s3Prefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+
+	return prefix + "/"
+}
+
+func s3Digest(objects map[string]string) string {
+	paths := make([]string, 0, len(objects))
+	for path := range objects {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s %s\n", objects[path], path)
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(h.Sum(nil)))
+}
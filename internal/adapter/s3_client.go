@@ -0,0 +1,263 @@
+package adapter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	s3_empty_payload_hash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3_time_format        = "20060102T150405Z"
+	s3_date_format        = "20060102"
+)
+
+type S3Object struct {
+	Key  string `xml:"Key"`
+	ETag string `xml:"ETag"`
+	Size int64  `xml:"Size"`
+}
+
+type s3ListBucketResult struct {
+	Contents              []S3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+// s3Client is a minimal client for S3 compatible object storages that signs its requests with AWS signature version 4.
+type s3Client struct {
+	client          *http.Client
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	pathStyle       bool
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// List returns all the objects under the given prefix.
+func (c *s3Client) List(prefix string) ([]S3Object, error) {
+	objects := []S3Object{}
+	token := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		res, err := c.do(http.MethodGet, "", query)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &s3ListBucketResult{}
+		err = func() error {
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				return c.error(res, fmt.Sprintf("Unexpected status while listing the bucket: %s/%s", c.bucket, prefix))
+			}
+
+			return xml.NewDecoder(res.Body).Decode(result)
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range result.Contents {
+			object.ETag = strings.Trim(object.ETag, `"`)
+			objects = append(objects, object)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return objects, nil
+}
+
+// Get downloads the object with the given key into the writer.
+func (c *s3Client) Get(key string, w io.Writer) error {
+	res, err := c.do(http.MethodGet, key, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return c.error(res, fmt.Sprintf("Unexpected status while downloading the object: %s/%s", c.bucket, key))
+	}
+
+	_, err = io.Copy(w, res.Body)
+
+	return err
+}
+
+func (c *s3Client) do(method string, key string, query url.Values) (*http.Response, error) {
+	u := *c.endpoint
+
+	if c.pathStyle {
+		u.Path = fmt.Sprintf("/%s/%s", c.bucket, key)
+	} else {
+		u.Host = fmt.Sprintf("%s.%s", c.bucket, c.endpoint.Host)
+		u.Path = fmt.Sprintf("/%s", key)
+	}
+
+	// the path is sent exactly in the form that is signed
+	u.RawPath = s3EscapePath(u.Path)
+
+	if query != nil {
+		u.RawQuery = s3CanonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sign(req, time.Now().UTC())
+
+	return c.client.Do(req)
+}
+
+// sign adds the AWS signature version 4 authorization to the request, anonymous requests are not signed.
+func (c *s3Client) sign(req *http.Request, now time.Time) {
+	if c.accessKeyId == "" {
+		return
+	}
+
+	req.Header.Set("X-Amz-Date", now.Format(s3_time_format))
+	req.Header.Set("X-Amz-Content-Sha256", s3_empty_payload_hash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for key := range req.Header {
+		if lower := strings.ToLower(key); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(key))
+		}
+	}
+
+	signed := make([]string, 0, len(headers))
+	for key := range headers {
+		signed = append(signed, key)
+	}
+	slices.Sort(signed)
+
+	canonicalHeaders := strings.Builder{}
+	for _, key := range signed {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", key, headers[key])
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		s3_empty_payload_hash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format(s3_date_format), c.region)
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(s3_time_format),
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := s3Hmac([]byte(fmt.Sprintf("AWS4%s", c.secretAccessKey)), now.Format(s3_date_format))
+	key = s3Hmac(key, c.region)
+	key = s3Hmac(key, "s3")
+	key = s3Hmac(key, "aws4_request")
+
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			c.accessKeyId,
+			scope,
+			strings.Join(signed, ";"),
+			hex.EncodeToString(s3Hmac(key, stringToSign)),
+		),
+	)
+}
+
+func (c *s3Client) error(res *http.Response, message string) error {
+	body := struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}{}
+
+	if err := xml.NewDecoder(res.Body).Decode(&body); err != nil || body.Code == "" {
+		return fmt.Errorf("%s -> %s", message, res.Status)
+	}
+
+	return fmt.Errorf("%s -> %s: %s %s", message, res.Status, body.Code, body.Message)
+}
+
+func s3Hmac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, fmt.Sprintf("%s=%s", s3Escape(key), s3Escape(value)))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// s3Escape encodes everything except the unreserved characters as required by the signature.
+func s3Escape(value string) string {
+	b := strings.Builder{}
+
+	for _, c := range []byte(value) {
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,358 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+const (
+	testS3Bucket   = "configs"
+	testS3PageSize = 2
+)
+
+// testS3Storage is an in-process object storage that serves a single bucket in pages of two objects.
+type testS3Storage struct {
+	server  *httptest.Server
+	objects map[string][]byte
+	// requests records the host and the path of every request, to assert the addressing style.
+	requests []string
+	// gets records the keys of the downloaded objects.
+	gets []string
+	mu   sync.Mutex
+}
+
+func newTestS3Storage(t *testing.T) *testS3Storage {
+	t.Helper()
+
+	s := &testS3Storage{
+		objects: map[string][]byte{},
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, req.Host+req.URL.Path)
+
+		if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")
+
+			return
+		}
+
+		// virtual-hosted style carries the bucket in the host, path-style in the first segment of the path
+		key, ok := strings.CutPrefix(req.URL.Path, fmt.Sprintf("/%s/", testS3Bucket))
+		if strings.HasPrefix(req.Host, testS3Bucket+".") {
+			key, ok = strings.CutPrefix(req.URL.Path, "/")
+		}
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>")
+
+			return
+		}
+
+		if key == "" {
+			s.list(w, req.URL.Query())
+
+			return
+		}
+
+		content, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist</Message></Error>")
+
+			return
+		}
+
+		s.gets = append(s.gets, key)
+
+		//nolint:errcheck
+		w.Write(content)
+	}))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+func (s *testS3Storage) list(w http.ResponseWriter, query url.Values) {
+	keys := []string{}
+	for key := range s.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	start := 0
+	if token := query.Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+
+	end := min(start+testS3PageSize, len(keys))
+
+	result := &s3ListBucketResult{}
+	for _, key := range keys[start:end] {
+		result.Contents = append(result.Contents, S3Object{Key: key, ETag: fmt.Sprintf(`"%s"`, testS3ETag(s.objects[key])), Size: int64(len(s.objects[key]))})
+	}
+
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+
+	//nolint:errcheck
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (s *testS3Storage) client(t *testing.T, pathStyle bool) *s3Client {
+	t.Helper()
+
+	endpoint, err := url.Parse(s.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every host is dialed to the test server, so that the bucket can be resolved as a subdomain
+	address := s.server.Listener.Addr().String()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return &s3Client{
+		client:          &http.Client{Transport: transport},
+		endpoint:        endpoint,
+		region:          "us-east-1",
+		bucket:          testS3Bucket,
+		pathStyle:       pathStyle,
+		accessKeyId:     "key",
+		secretAccessKey: "secret",
+	}
+}
+
+func (s *testS3Storage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.gets = nil
+}
+
+func testS3ETag(content []byte) string {
+	h := md5.Sum(content)
+
+	return hex.EncodeToString(h[:])
+}
+
+func TestS3ClientList(t *testing.T) {
+	s := newTestS3Storage(t)
+	for _, key := range []string{"app/a.conf", "app/b.conf", "app/c.conf", "app/d/e.conf", "app/f.conf", "other/g.conf"} {
+		s.objects[key] = []byte(key)
+	}
+
+	tests := []struct {
+		name      string
+		pathStyle bool
+		host      string
+		path      string
+	}{
+		{name: "path-style", pathStyle: true, host: s.server.Listener.Addr().String(), path: fmt.Sprintf("/%s/", testS3Bucket)},
+		{name: "virtual-hosted", host: fmt.Sprintf("%s.%s", testS3Bucket, s.server.Listener.Addr().String()), path: "/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s.reset()
+
+			objects, err := s.client(t, test.pathStyle).List("app/")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			keys := []string{}
+			for _, object := range objects {
+				keys = append(keys, object.Key)
+
+				if object.ETag != testS3ETag(s.objects[object.Key]) {
+					t.Fatalf("expected the ETag of %s without quotes, got %s", object.Key, object.ETag)
+				}
+			}
+
+			expected := []string{"app/a.conf", "app/b.conf", "app/c.conf", "app/d/e.conf", "app/f.conf"}
+			if !slices.Equal(keys, expected) {
+				t.Fatalf("expected %v, got %v", expected, keys)
+			}
+
+			// five objects are listed in three pages
+			if len(s.requests) != 3 {
+				t.Fatalf("expected 3 list requests, got %d", len(s.requests))
+			}
+
+			for _, request := range s.requests {
+				if request != test.host+test.path {
+					t.Fatalf("expected request to %s%s, got %s", test.host, test.path, request)
+				}
+			}
+		})
+	}
+}
+
+func TestS3ClientGet(t *testing.T) {
+	s := newTestS3Storage(t)
+	s.objects["app/with space+plus.conf"] = []byte("content")
+
+	for _, pathStyle := range []bool{true, false} {
+		t.Run(fmt.Sprintf("path-style=%t", pathStyle), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			if err := s.client(t, pathStyle).Get("app/with space+plus.conf", buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if buf.String() != "content" {
+				t.Fatalf("expected %q, got %q", "content", buf.String())
+			}
+
+			err := s.client(t, pathStyle).Get("app/missing.conf", buf)
+			if err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+				t.Fatalf("expected the error code of the storage, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestS3AdapterDownload(t *testing.T) {
+	s := newTestS3Storage(t)
+	s.objects["app/a.conf"] = []byte("a")
+	s.objects["app/b.conf"] = []byte("b")
+	s.objects["app/nested/c.conf"] = []byte("c")
+	s.objects["app/nested/"] = []byte{}
+	s.objects["app-old/d.conf"] = []byte("d")
+	s.objects["application.conf"] = []byte("e")
+
+	dir := t.TempDir()
+	a := &S3Adapter{
+		client:           s.client(t, true),
+		config:           &S3AdapterConfig{Bucket: testS3Bucket, Prefix: "app"},
+		prefix:           s3Prefix("app"),
+		workingDirectory: dir,
+	}
+	task := &Task[any]{Log: logrus.NewEntry(logrus.New())}
+
+	state, err := a.download(task, &S3AdapterState{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(state.Objects) != 3 {
+		t.Fatalf("expected 3 objects without the directory marker and the siblings of the prefix, got %v", state.Objects)
+	}
+
+	for path, expected := range map[string]string{"a.conf": "a", "b.conf": "b", "nested/c.conf": "c"} {
+		content, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(content) != expected {
+			t.Fatalf("expected %q in %s, got %q", expected, path, content)
+		}
+	}
+
+	// an unchanged listing downloads nothing and keeps the digest
+	s.reset()
+
+	unchanged, err := a.download(task, state)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(s.gets) != 0 {
+		t.Fatalf("expected no downloads for the same ETags, got %v", s.gets)
+	}
+
+	if unchanged.Digest != state.Digest || !unchanged.Time.Equal(state.Time) {
+		t.Fatalf("expected the same digest and time, got %s at %s", unchanged.Digest, unchanged.Time)
+	}
+
+	// a changed object is downloaded again, a removed object is deleted and a missing file is restored
+	s.reset()
+	s.objects["app/a.conf"] = []byte("changed")
+	delete(s.objects, "app/b.conf")
+
+	if err := os.Remove(filepath.Join(dir, "nested", "c.conf")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := a.download(task, unchanged)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slices.Sort(s.gets)
+	if expected := []string{"app/a.conf", "app/nested/c.conf"}; !slices.Equal(s.gets, expected) {
+		t.Fatalf("expected downloads of %v, got %v", expected, s.gets)
+	}
+
+	if changed.Digest == unchanged.Digest {
+		t.Fatal("expected a different digest after the changes")
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, "a.conf")); err != nil || string(content) != "changed" {
+		t.Fatalf("expected the changed content, got %q -> %v", content, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "b.conf")); !os.IsNotExist(err) {
+		t.Fatalf("expected the removed object to be deleted: %v", err)
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected only a.conf and nested to remain, got %d entries", len(entries))
+	}
+}
+
+func TestS3Prefix(t *testing.T) {
+	tests := map[string]string{
+		"":          "",
+		"/":         "",
+		"app":       "app/",
+		"app/":      "app/",
+		"/app/conf": "app/conf/",
+	}
+
+	for prefix, expected := range tests {
+		if actual := s3Prefix(prefix); actual != expected {
+			t.Fatalf("expected %q for %q, got %q", expected, prefix, actual)
+		}
+	}
+}
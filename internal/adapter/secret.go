@@ -0,0 +1,21 @@
+package adapter
+
+import (
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+)
+
+// readSecret returns the secret directly if it is given, otherwise reads it from the file following the _FILE convention.
+func readSecret(value string, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+
+	data, err := operations.NewFile(file).ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
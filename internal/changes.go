@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type ChangeAction = string
+
+const (
+	CHANGE_ACTION_CREATE ChangeAction = "create"
+	CHANGE_ACTION_UPDATE ChangeAction = "update"
+	CHANGE_ACTION_CHMOD  ChangeAction = "chmod"
+	CHANGE_ACTION_DELETE ChangeAction = "delete"
+	CHANGE_ACTION_MKDIR  ChangeAction = "mkdir"
+
+	CHANGE_ACTION_UNCHANGED ChangeAction = "unchanged"
+	CHANGE_ACTION_TEMPLATED ChangeAction = "templated"
+	CHANGE_ACTION_IGNORED   ChangeAction = "ignored"
+	CHANGE_ACTION_CONFLICT  ChangeAction = "conflict"
+)
+
+type Change struct {
+	Action ChangeAction `json:"action"`
+	Path   string       `json:"path"`
+	Mode   os.FileMode  `json:"mode,omitempty"`
+	Diff   string       `json:"diff,omitempty"`
+}
+
+// Changes records the changes that are applied or planned to the target directory in a sync cycle,
+// together with the errors that did not stop the cycle.
+type Changes struct {
+	changes []Change
+	errors  []error
+	// pending are the errors that happened outside of a sync cycle, which are reported with the next one.
+	pending []error
+	mu      sync.Mutex
+}
+
+func NewChanges() *Changes {
+	return &Changes{
+		changes: []Change{},
+		errors:  []error{},
+	}
+}
+
+func (c *Changes) AddError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors = append(c.errors, err)
+}
+
+// AddPendingError records an error that happened outside of a sync cycle, e.g. while initializing the adapter,
+// which is carried over into the next cycle instead of being dropped by its reset.
+func (c *Changes) AddPendingError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = append(c.pending, err)
+}
+
+func (c *Changes) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.errors)
+}
+
+func (c *Changes) Add(change Change) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.changes = append(c.changes, change)
+}
+
+// List returns the recorded changes ordered by their path.
+func (c *Changes) List() []Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes := slices.Clone(c.changes)
+	slices.SortStableFunc(changes, func(a Change, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return changes
+}
+
+// Paths returns the paths of the changes with the given action in order.
+func (c *Changes) Paths(action ChangeAction) []string {
+	paths := []string{}
+	for _, change := range c.List() {
+		if change.Action == action {
+			paths = append(paths, change.Path)
+		}
+	}
+
+	return paths
+}
+
+// Count returns the number of changes with the given action.
+func (c *Changes) Count(action ChangeAction) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, change := range c.changes {
+		if change.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Reset starts a new sync cycle, where the pending errors are recorded as the first errors of the cycle.
+func (c *Changes) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.changes = []Change{}
+	c.errors = append([]error{}, c.pending...)
+	c.pending = nil
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestChangesPendingErrors(t *testing.T) {
+	changes := NewChanges()
+
+	// the error of the initialization is recorded before the first cycle resets the changes
+	initialization := errors.New("initialization")
+	changes.AddPendingError(initialization)
+
+	if errs := changes.Errors(); len(errs) != 0 {
+		t.Fatalf("expected no errors before the cycle, got %v", errs)
+	}
+
+	changes.Reset()
+	changes.AddError(errors.New("cycle"))
+
+	errs := changes.Errors()
+	if len(errs) != 2 || !errors.Is(errs[0], initialization) {
+		t.Fatalf("expected the pending error first in the cycle, got %v", errs)
+	}
+
+	// the pending error is only reported with the first cycle
+	changes.Reset()
+
+	if errs := changes.Errors(); len(errs) != 0 {
+		t.Fatalf("expected no errors in the next cycle, got %v", errs)
+	}
+}
+
+func TestChangesPaths(t *testing.T) {
+	changes := NewChanges()
+	changes.Add(Change{Action: CHANGE_ACTION_CREATE, Path: "/b"})
+	changes.Add(Change{Action: CHANGE_ACTION_DELETE, Path: "/c"})
+	changes.Add(Change{Action: CHANGE_ACTION_CREATE, Path: "/a"})
+
+	if paths := changes.Paths(CHANGE_ACTION_CREATE); !slices.Equal(paths, []string{"/a", "/b"}) {
+		t.Fatalf("expected the sorted created paths, got %v", paths)
+	}
+
+	changes.Reset()
+
+	if count := changes.Count(CHANGE_ACTION_CREATE); count != 0 {
+		t.Fatalf("expected no changes after the reset, got %d", count)
+	}
+}
@@ -0,0 +1,53 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandHook runs a shell command in the directory of the event, where the changed files are passed through the environment and the standard input.
+type CommandHook struct {
+	command string
+}
+
+var _ Hook = (*CommandHook)(nil)
+
+func NewCommandHook(command string) *CommandHook {
+	return &CommandHook{
+		command: command,
+	}
+}
+
+func (h *CommandHook) Run(ctx context.Context, event *Event) error {
+	files := strings.Join(event.Files, "\n")
+
+	//nolint: gosec
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Dir = event.Directory
+	cmd.Env = append(
+		os.Environ(),
+		fmt.Sprintf("%s=%s", ENV_DIRECTORY, event.Directory),
+		fmt.Sprintf("%s=%s", ENV_CHANGED_FILES, files),
+	)
+	if event.Revision != nil {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", ENV_REVISION, event.Revision.Revision))
+	}
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("%s\n", files))
+	// the children of the shell might still hold the output open after the timeout
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	if output := strings.TrimSpace(string(out)); err != nil && output != "" {
+		return fmt.Errorf("%w: %s", err, output)
+	}
+
+	return err
+}
+
+func (h *CommandHook) String() string {
+	return fmt.Sprintf("%s: %s", HOOK_TYPE_COMMAND, h.command)
+}
@@ -0,0 +1,20 @@
+package hooks
+
+import "time"
+
+type HookType = string
+
+const (
+	HOOK_TYPE_COMMAND HookType = "command"
+	HOOK_TYPE_SIGNAL  HookType = "signal"
+	HOOK_TYPE_HTTP    HookType = "http"
+)
+
+const (
+	ENV_DIRECTORY     = "BEAMER_DIRECTORY"
+	ENV_CHANGED_FILES = "BEAMER_CHANGED_FILES"
+	ENV_REVISION      = "BEAMER_REVISION"
+
+	DEFAULT_TIMEOUT     = 30 * time.Second
+	DEFAULT_RETRY_DELAY = 5 * time.Second
+)
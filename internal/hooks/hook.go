@@ -0,0 +1,55 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Definition is a hook together with its execution options.
+type Definition struct {
+	Hook       Hook
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+}
+
+func (d *Definition) String() string {
+	return d.Hook.String()
+}
+
+// Run executes the hook, where every attempt is limited by the timeout and failed attempts are retried after the delay.
+func (d *Definition) Run(ctx context.Context, log *logrus.Entry, event *Event) error {
+	var err error
+
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("Retrying hook in %s: %s (%d/%d) -> %v", d.RetryDelay, d, attempt, d.Retries, err)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.RetryDelay):
+			}
+		}
+
+		err = d.attempt(ctx, event)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Hook failed after %d attempts: %s -> %w", d.Retries+1, d, err)
+}
+
+func (d *Definition) attempt(ctx context.Context, event *Event) error {
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+
+	return d.Hook.Run(ctx, event)
+}
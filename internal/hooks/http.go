@@ -0,0 +1,69 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HttpHook sends the event as JSON to an endpoint.
+type HttpHook struct {
+	method string
+	url    string
+}
+
+var _ Hook = (*HttpHook)(nil)
+
+func NewHttpHook(method string, url string) *HttpHook {
+	return &HttpHook{
+		method: method,
+		url:    url,
+	}
+}
+
+// ParseHttpHook parses a hook in the format of an URL with an optional method prefix, e.g. PUT http://localhost:8080/reload,
+// where the method defaults to POST.
+func ParseHttpHook(value string) (*HttpHook, error) {
+	fields := strings.Fields(value)
+
+	switch len(fields) {
+	case 1:
+		return NewHttpHook(http.MethodPost, fields[0]), nil
+	case 2:
+		return NewHttpHook(strings.ToUpper(fields[0]), fields[1]), nil
+	}
+
+	return nil, fmt.Errorf("HTTP hook should be in the format of [method] url: %s", value)
+}
+
+func (h *HttpHook) Run(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, h.method, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected status from the endpoint: %s", res.Status)
+	}
+
+	return nil
+}
+
+func (h *HttpHook) String() string {
+	return fmt.Sprintf("%s: %s %s", HOOK_TYPE_HTTP, h.method, h.url)
+}
@@ -0,0 +1,20 @@
+package hooks
+
+import (
+	"context"
+
+	"gitlab.kilic.dev/docker/beamer/internal/adapter"
+)
+
+type Hook interface {
+	Run(ctx context.Context, event *Event) error
+	String() string
+}
+
+// Event describes the sync cycle that triggered the hooks.
+type Event struct {
+	// Directory is the directory that contains the files, which is the staging directory for the validation hooks.
+	Directory string            `json:"directory"`
+	Files     []string          `json:"files"`
+	Revision  *adapter.Revision `json:"revision,omitempty"`
+}
@@ -0,0 +1,143 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SignalHook sends a signal to a process that is found through its pidfile or by its name in a shared PID namespace.
+type SignalHook struct {
+	signal  syscall.Signal
+	pidFile string
+	process string
+}
+
+var _ Hook = (*SignalHook)(nil)
+
+func NewSignalHook(signal syscall.Signal, pidFile string, process string) *SignalHook {
+	return &SignalHook{
+		signal:  signal,
+		pidFile: pidFile,
+		process: process,
+	}
+}
+
+// ParseSignalHook parses a hook in the format of signal:target, where the target is a pidfile when it is an absolute path
+// and a process name otherwise, e.g. SIGHUP:/run/nginx.pid or HUP:nginx.
+func ParseSignalHook(value string) (*SignalHook, error) {
+	name, target, ok := strings.Cut(value, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("Signal hook should be in the format of signal:target: %s", value)
+	}
+
+	signal, err := ParseSignal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.IsAbs(target) {
+		return NewSignalHook(signal, target, ""), nil
+	}
+
+	return NewSignalHook(signal, "", target), nil
+}
+
+// ParseSignal parses the name of a signal with or without the SIG prefix.
+func ParseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = fmt.Sprintf("SIG%s", name)
+	}
+
+	signal := unix.SignalNum(name)
+	if signal == 0 {
+		return 0, fmt.Errorf("Signal is not supported: %s", name)
+	}
+
+	return signal, nil
+}
+
+func (h *SignalHook) Run(_ context.Context, _ *Event) error {
+	pids, err := h.pids()
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, h.signal); err != nil {
+			return fmt.Errorf("Can not send the signal to the process: %d -> %w", pid, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *SignalHook) String() string {
+	if h.pidFile != "" {
+		return fmt.Sprintf("%s: %s to pidfile %s", HOOK_TYPE_SIGNAL, unix.SignalName(h.signal), h.pidFile)
+	}
+
+	return fmt.Sprintf("%s: %s to process %s", HOOK_TYPE_SIGNAL, unix.SignalName(h.signal), h.process)
+}
+
+func (h *SignalHook) pids() ([]int, error) {
+	if h.pidFile != "" {
+		content, err := os.ReadFile(h.pidFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("Pidfile does not contain a valid pid: %s -> %w", h.pidFile, err)
+		}
+
+		return []int{pid}, nil
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+
+		if h.matches(pid) {
+			pids = append(pids, pid)
+		}
+	}
+
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("No process found with the name: %s", h.process)
+	}
+
+	return pids, nil
+}
+
+// matches checks the name of the process and the executable of its command line, since the name is truncated by the kernel.
+func (h *SignalHook) matches(pid int) bool {
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil && strings.TrimSpace(string(comm)) == h.process {
+		return true
+	}
+
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return false
+	}
+
+	executable, _, _ := strings.Cut(string(cmdline), "\x00")
+
+	return executable != "" && filepath.Base(executable) == h.process
+}
@@ -0,0 +1,87 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+)
+
+// Filter combines the ignore files of the source with the include and exclude patterns of the operator.
+//
+// The exclude patterns take precedence over everything, then the ignore files decide and the include patterns
+// narrow down the remaining files at last, where directories are only pruned by the exclude patterns and the ignore files.
+//
+// Like in the ignore files, an include or exclude pattern without a separator matches the name at any level.
+type Filter struct {
+	matcher *Matcher
+	include []string
+	exclude []string
+}
+
+// Decision is the result of filtering a path together with the rule that has decided it.
+type Decision struct {
+	Ignored bool
+	Rule    string
+}
+
+func NewFilter(matcher *Matcher, include []string, exclude []string) (*Filter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if !glob.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("Filter pattern is not valid: %s", pattern)
+		}
+	}
+
+	return &Filter{
+		matcher: matcher,
+		include: include,
+		exclude: exclude,
+	}, nil
+}
+
+func (f *Filter) Matcher() *Matcher {
+	return f.matcher
+}
+
+// Match decides whether the path relative to the walked root is ignored.
+func (f *Filter) Match(file string, isDir bool) Decision {
+	for _, pattern := range f.exclude {
+		if matchFilterPattern(pattern, file) {
+			return Decision{Ignored: true, Rule: fmt.Sprintf("exclude: %s", pattern)}
+		}
+	}
+
+	ignored, pattern := f.matcher.Match(file, isDir)
+	if ignored {
+		return Decision{Ignored: true, Rule: pattern.String()}
+	}
+
+	rule := "default"
+	if pattern != nil {
+		rule = pattern.String()
+	}
+
+	if isDir || len(f.include) == 0 {
+		return Decision{Ignored: false, Rule: rule}
+	}
+
+	for _, pattern := range f.include {
+		if matchFilterPattern(pattern, file) {
+			return Decision{Ignored: false, Rule: fmt.Sprintf("include: %s", pattern)}
+		}
+	}
+
+	return Decision{Ignored: true, Rule: "include: no pattern matches"}
+}
+
+// matchFilterPattern matches the pattern against the path, or against its name at any level when the pattern has no separator.
+func matchFilterPattern(pattern string, file string) bool {
+	if !strings.Contains(pattern, "/") {
+		file = path.Base(file)
+	}
+
+	match, _ := glob.Match(pattern, file)
+
+	return match
+}
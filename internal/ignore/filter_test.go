@@ -0,0 +1,71 @@
+package ignore
+
+import (
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	matcher := NewMatcher()
+	if err := matcher.AddLines("", ".beamer-ignore", []string{"*.tmp", "!keep.tmp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		file    string
+		isDir   bool
+		ignored bool
+		rule    string
+	}{
+		{name: "default", file: "app.conf", rule: "default"},
+		{name: "ignore file", file: "nested/a.tmp", ignored: true, rule: ".beamer-ignore:1: *.tmp"},
+		{name: "ignore file negation", file: "nested/keep.tmp", rule: ".beamer-ignore:2: !keep.tmp"},
+
+		{name: "exclude name at the root", exclude: []string{"*.bak"}, file: "a.bak", ignored: true, rule: "exclude: *.bak"},
+		{name: "exclude name at any level", exclude: []string{"*.bak"}, file: "nginx/conf.d/a.bak", ignored: true, rule: "exclude: *.bak"},
+		{name: "exclude directory name at any level", exclude: []string{"secrets"}, file: "app/secrets", isDir: true, ignored: true, rule: "exclude: secrets"},
+		{name: "exclude path is anchored", exclude: []string{"nginx/*.bak"}, file: "other/nginx/a.bak", rule: "default"},
+		{name: "exclude path", exclude: []string{"nginx/*.bak"}, file: "nginx/a.bak", ignored: true, rule: "exclude: nginx/*.bak"},
+		{name: "exclude double star", exclude: []string{"**/cache/**"}, file: "a/cache/b/c", ignored: true, rule: "exclude: **/cache/**"},
+		{name: "exclude takes precedence over the negation", exclude: []string{"keep.tmp"}, file: "keep.tmp", ignored: true, rule: "exclude: keep.tmp"},
+
+		{name: "include name at the root", include: []string{"*.conf"}, file: "a.conf", rule: "include: *.conf"},
+		{name: "include name at any level", include: []string{"*.conf"}, file: "nginx/conf.d/a.conf", rule: "include: *.conf"},
+		{name: "include no match", include: []string{"*.conf"}, file: "nginx/mime.types", ignored: true, rule: "include: no pattern matches"},
+		{name: "include path is anchored", include: []string{"nginx/*.conf"}, file: "other/nginx/a.conf", ignored: true, rule: "include: no pattern matches"},
+		{name: "include path", include: []string{"nginx/*.conf"}, file: "nginx/a.conf", rule: "include: nginx/*.conf"},
+		{name: "include walks directories", include: []string{"*.conf"}, file: "nginx", isDir: true, rule: "default"},
+		{name: "include does not override the ignore file", include: []string{"*.tmp"}, file: "a.tmp", ignored: true, rule: ".beamer-ignore:1: *.tmp"},
+		{name: "include does not override the exclude", include: []string{"*.conf"}, exclude: []string{"old.conf"}, file: "nginx/old.conf", ignored: true, rule: "exclude: old.conf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewFilter(matcher, test.include, test.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			decision := filter.Match(test.file, test.isDir)
+			if decision.Ignored != test.ignored {
+				t.Fatalf("expected ignored to be %t for %s, got %t with %s", test.ignored, test.file, decision.Ignored, decision.Rule)
+			}
+
+			if decision.Rule != test.rule {
+				t.Fatalf("expected rule %q, got %q", test.rule, decision.Rule)
+			}
+		})
+	}
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	if _, err := NewFilter(NewMatcher(), []string{"[a"}, nil); err == nil {
+		t.Fatal("expected an error for an invalid include pattern")
+	}
+
+	if _, err := NewFilter(NewMatcher(), nil, []string{"[a"}); err == nil {
+		t.Fatal("expected an error for an invalid exclude pattern")
+	}
+}
@@ -0,0 +1,67 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+)
+
+// Matcher decides whether a path is ignored by the rules of the ignore files, where the last matching rule wins.
+//
+// The parents of a path are not considered, so the ignored directories have to be pruned while walking,
+// which also means that a file can not be re-included when its directory is ignored, like in git.
+type Matcher struct {
+	patterns []*Pattern
+}
+
+func NewMatcher() *Matcher {
+	return &Matcher{
+		patterns: []*Pattern{},
+	}
+}
+
+// AddLines parses the lines of an ignore file in the given directory relative to the walked root.
+//
+// The rules of the nested ignore files have to be added after the ones of their parents to take precedence.
+func (m *Matcher) AddLines(base string, source string, lines []string) error {
+	for i, line := range lines {
+		pattern, err := ParsePattern(base, fmt.Sprintf("%s:%d", source, i+1), line)
+		if err != nil {
+			return err
+		} else if pattern == nil {
+			continue
+		}
+
+		m.patterns = append(m.patterns, pattern)
+	}
+
+	return nil
+}
+
+// Add adds a built-in rule that applies to the whole walked root.
+func (m *Matcher) Add(source string, line string) error {
+	pattern, err := ParsePattern("", source, line)
+	if err != nil || pattern == nil {
+		return err
+	}
+
+	m.patterns = append(m.patterns, pattern)
+
+	return nil
+}
+
+// Match returns whether the path relative to the walked root is ignored, together with the rule that has decided it.
+func (m *Matcher) Match(file string, isDir bool) (bool, *Pattern) {
+	file = path.Clean(file)
+
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if m.patterns[i].Match(file, isDir) {
+			return !m.patterns[i].IsNegated(), m.patterns[i]
+		}
+	}
+
+	return false, nil
+}
+
+func (m *Matcher) Patterns() []*Pattern {
+	return m.patterns
+}
@@ -0,0 +1,184 @@
+package ignore
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string][]string
+		file    string
+		isDir   bool
+		ignored bool
+		rule    string
+	}{
+		{
+			name:  "no rules",
+			files: map[string][]string{"": {}},
+			file:  "a.log",
+		},
+		{
+			name:    "ignored",
+			files:   map[string][]string{"": {"*.log"}},
+			file:    "a.log",
+			ignored: true,
+			rule:    ".beamer-ignore:1: *.log",
+		},
+		{
+			name:  "negation re-includes the file",
+			files: map[string][]string{"": {"*.log", "!keep.log"}},
+			file:  "a/keep.log",
+			rule:  ".beamer-ignore:2: !keep.log",
+		},
+		{
+			name:    "last matching rule wins",
+			files:   map[string][]string{"": {"!keep.log", "*.log"}},
+			file:    "keep.log",
+			ignored: true,
+			rule:    ".beamer-ignore:2: *.log",
+		},
+		{
+			name:    "directory only",
+			files:   map[string][]string{"": {"logs/"}},
+			file:    "a/logs",
+			isDir:   true,
+			ignored: true,
+			rule:    ".beamer-ignore:1: logs/",
+		},
+		{
+			name:  "directory only does not ignore files",
+			files: map[string][]string{"": {"logs/"}},
+			file:  "logs",
+		},
+		{
+			name:  "nested ignore file takes precedence",
+			files: map[string][]string{"": {"*.log"}, "app": {"!*.log"}},
+			file:  "app/a.log",
+			rule:  "app/.beamer-ignore:1: !*.log",
+		},
+		{
+			name:    "nested ignore file is scoped to its directory",
+			files:   map[string][]string{"": {"*.log"}, "app": {"!*.log"}},
+			file:    "other/a.log",
+			ignored: true,
+			rule:    ".beamer-ignore:1: *.log",
+		},
+		{
+			name:    "path is cleaned",
+			files:   map[string][]string{"": {"/a/b"}},
+			file:    "a/./c/../b",
+			ignored: true,
+			rule:    ".beamer-ignore:1: /a/b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := NewMatcher()
+
+			// the parents are added before the nested ignore files, like while walking
+			dirs := []string{}
+			for dir := range test.files {
+				dirs = append(dirs, dir)
+			}
+			slices.Sort(dirs)
+
+			for _, dir := range dirs {
+				if err := m.AddLines(dir, path.Join(dir, ".beamer-ignore"), test.files[dir]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ignored, pattern := m.Match(test.file, test.isDir)
+			if ignored != test.ignored {
+				t.Fatalf("expected ignored to be %t for %s, got %t with %v", test.ignored, test.file, ignored, pattern)
+			}
+
+			rule := ""
+			if pattern != nil {
+				rule = pattern.String()
+			}
+
+			if rule != test.rule {
+				t.Fatalf("expected rule %q, got %q", test.rule, rule)
+			}
+		})
+	}
+}
+
+// TestMatcherPruning walks the directories like the sync does, where the ignored directories are not walked.
+func TestMatcherPruning(t *testing.T) {
+	fsys := fstest.MapFS{
+		".beamer-ignore":           {Data: []byte("logs/\n*.tmp\n.beamer-ignore\n")},
+		"app.conf":                 {},
+		"a.tmp":                    {},
+		"logs/a.log":               {},
+		"logs/.beamer-ignore":      {Data: []byte("!*\n")},
+		"logs/keep.log":            {},
+		"nested/.beamer-ignore":    {Data: []byte("!keep.tmp\n/local.conf\n")},
+		"nested/keep.tmp":          {},
+		"nested/other.tmp":         {},
+		"nested/local.conf":        {},
+		"nested/deeper/local.conf": {},
+		"nested/deeper/keep.tmp":   {},
+	}
+
+	m := NewMatcher()
+	if err := m.AddLines("", ".beamer-ignore", readTestIgnoreFile(t, fsys, ".beamer-ignore")); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := NewFilter(m, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{}
+	err = fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || file == "." {
+			return err
+		}
+
+		if filter.Match(file, d.IsDir()).Ignored {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			return m.AddLines(file, path.Join(file, ".beamer-ignore"), readTestIgnoreFile(t, fsys, path.Join(file, ".beamer-ignore")))
+		}
+
+		files = append(files, file)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the negation in logs can not re-include its files, since the directory is excluded by the parent and never walked
+	expected := []string{"app.conf", "nested/deeper/keep.tmp", "nested/deeper/local.conf", "nested/keep.tmp"}
+	if !slices.Equal(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+}
+
+func readTestIgnoreFile(t *testing.T, fsys fs.FS, file string) []string {
+	t.Helper()
+
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
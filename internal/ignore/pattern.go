@@ -0,0 +1,112 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+)
+
+// Pattern is a single rule of an ignore file in the gitignore format.
+type Pattern struct {
+	// Base is the directory of the ignore file relative to the walked root, which is empty for the root itself.
+	Base string
+	// Source is the location of the rule for reporting, like the ignore file and the line.
+	Source string
+	// Line is the rule as it is written.
+	Line string
+
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ParsePattern parses a line of an ignore file, where blank lines and comments do not yield a pattern.
+func ParsePattern(base string, source string, line string) (*Pattern, error) {
+	p := &Pattern{
+		Base:   strings.Trim(base, "/"),
+		Source: source,
+		Line:   line,
+	}
+
+	value := trimTrailingSpaces(line)
+	if value == "" || strings.HasPrefix(value, "#") {
+		return nil, nil
+	}
+
+	// escaped leading characters are kept, since the glob matches them literally
+	if strings.HasPrefix(value, "!") {
+		p.negate = true
+		value = value[1:]
+	}
+
+	if strings.HasSuffix(value, "/") {
+		p.dirOnly = true
+		value = strings.TrimRight(value, "/")
+	}
+
+	// a separator at the beginning or in the middle anchors the pattern to the directory of the ignore file
+	if strings.Contains(value, "/") {
+		p.anchored = true
+		value = strings.TrimPrefix(value, "/")
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	if !glob.ValidatePattern(value) {
+		return nil, fmt.Errorf("Ignore pattern is not valid: %s -> %s", source, line)
+	}
+
+	p.glob = value
+
+	return p, nil
+}
+
+// Match checks whether the pattern applies to the path, which is relative to the walked root.
+func (p *Pattern) Match(file string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	rel := file
+	if p.Base != "" {
+		if !strings.HasPrefix(file, p.Base+"/") {
+			return false
+		}
+
+		rel = strings.TrimPrefix(file, p.Base+"/")
+	}
+
+	if !p.anchored {
+		// patterns without a separator match the name at any level
+		match, _ := glob.Match(p.glob, path.Base(rel))
+
+		return match
+	}
+
+	match, _ := glob.Match(p.glob, rel)
+
+	return match
+}
+
+// IsNegated checks whether the pattern re-includes the paths that it matches.
+func (p *Pattern) IsNegated() bool {
+	return p.negate
+}
+
+func (p *Pattern) String() string {
+	return fmt.Sprintf("%s: %s", p.Source, p.Line)
+}
+
+// trimTrailingSpaces removes the trailing spaces of the line unless they are escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
@@ -0,0 +1,115 @@
+package ignore
+
+import (
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		empty    bool
+		err      bool
+		glob     string
+		negate   bool
+		dirOnly  bool
+		anchored bool
+	}{
+		{name: "blank", line: "", empty: true},
+		{name: "spaces", line: "   ", empty: true},
+		{name: "comment", line: "# comment", empty: true},
+		{name: "name", line: "*.log", glob: "*.log"},
+		{name: "trailing spaces", line: "*.log  ", glob: "*.log"},
+		{name: "escaped trailing space", line: `a\ `, glob: `a\ `},
+		{name: "escaped hash", line: `\#file`, glob: `\#file`},
+		{name: "escaped exclamation", line: `\!file`, glob: `\!file`},
+		{name: "negation", line: "!keep.log", glob: "keep.log", negate: true},
+		{name: "directory only", line: "logs/", glob: "logs", dirOnly: true},
+		{name: "leading separator", line: "/build", glob: "build", anchored: true},
+		{name: "separator in the middle", line: "a/b", glob: "a/b", anchored: true},
+		{name: "anchored directory", line: "/logs/", glob: "logs", dirOnly: true, anchored: true},
+		{name: "negated directory", line: "!logs/", glob: "logs", negate: true, dirOnly: true},
+		{name: "only a separator", line: "/", empty: true},
+		{name: "invalid", line: "[a", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := ParsePattern("", ".beamer-ignore:1", test.line)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %+v", p)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.empty {
+				if p != nil {
+					t.Fatalf("expected no pattern, got: %+v", p)
+				}
+
+				return
+			} else if p == nil {
+				t.Fatal("expected a pattern")
+			}
+
+			if p.glob != test.glob || p.negate != test.negate || p.dirOnly != test.dirOnly || p.anchored != test.anchored {
+				t.Fatalf(
+					"expected glob=%q negate=%t dirOnly=%t anchored=%t, got glob=%q negate=%t dirOnly=%t anchored=%t",
+					test.glob, test.negate, test.dirOnly, test.anchored,
+					p.glob, p.negate, p.dirOnly, p.anchored,
+				)
+			}
+		})
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		line  string
+		file  string
+		isDir bool
+		match bool
+	}{
+		{name: "name at the root", line: "*.log", file: "a.log", match: true},
+		{name: "name at any level", line: "*.log", file: "a/b/c.log", match: true},
+		{name: "name does not match", line: "*.log", file: "a/b/c.txt"},
+		{name: "anchored at the root", line: "/build", file: "build", isDir: true, match: true},
+		{name: "anchored does not match nested", line: "/build", file: "src/build", isDir: true},
+		{name: "path with separator is anchored", line: "a/*.log", file: "x/a/b.log"},
+		{name: "path with separator", line: "a/*.log", file: "a/b.log", match: true},
+		{name: "double star", line: "**/cache", file: "a/b/cache", isDir: true, match: true},
+		{name: "directory only matches directories", line: "logs/", file: "logs", isDir: true, match: true},
+		{name: "directory only skips files", line: "logs/", file: "logs"},
+		{name: "directory only at any level", line: "logs/", file: "a/logs", isDir: true, match: true},
+		{name: "escaped hash", line: `\#file`, file: "#file", match: true},
+		{name: "escaped exclamation", line: `\!file`, file: "!file", match: true},
+		{name: "escaped trailing space", line: `a\ `, file: "a ", match: true},
+		{name: "escaped trailing space does not match without it", line: `a\ `, file: "a"},
+
+		{name: "nested base scopes the name", base: "nginx", line: "*.bak", file: "nginx/conf.d/a.bak", match: true},
+		{name: "nested base does not match outside", base: "nginx", line: "*.bak", file: "other/a.bak"},
+		{name: "nested base does not match a sibling prefix", base: "nginx", line: "*.bak", file: "nginx-old/a.bak"},
+		{name: "nested base anchors to its directory", base: "nginx", line: "/a.conf", file: "nginx/a.conf", match: true},
+		{name: "nested base anchored does not match deeper", base: "nginx", line: "/a.conf", file: "nginx/conf.d/a.conf"},
+		{name: "nested base path", base: "nginx", line: "conf.d/*.conf", file: "nginx/conf.d/a.conf", match: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := ParsePattern(test.base, ".beamer-ignore:1", test.line)
+			if err != nil || p == nil {
+				t.Fatalf("expected a pattern: %v", err)
+			}
+
+			if match := p.Match(test.file, test.isDir); match != test.match {
+				t.Fatalf("expected %s to match %s with %t, got %t", test.line, test.file, test.match, match)
+			}
+		})
+	}
+}
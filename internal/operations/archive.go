@@ -0,0 +1,201 @@
+package operations
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ArchiveFormat = string
+
+const (
+	ARCHIVE_FORMAT_TAR_GZ ArchiveFormat = "tar.gz"
+	ARCHIVE_FORMAT_TAR    ArchiveFormat = "tar"
+	ARCHIVE_FORMAT_ZIP    ArchiveFormat = "zip"
+)
+
+// DetectArchiveFormat guesses the archive format from the file name.
+func DetectArchiveFormat(name string) (ArchiveFormat, error) {
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ARCHIVE_FORMAT_TAR_GZ, nil
+	case strings.HasSuffix(name, ".tar"):
+		return ARCHIVE_FORMAT_TAR, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ARCHIVE_FORMAT_ZIP, nil
+	}
+
+	return "", fmt.Errorf("Can not detect archive format: %s", name)
+}
+
+// ExtractArchive extracts the archive into the destination directory and returns the extracted file paths relative to the destination.
+// Leading path components can be stripped with strip, in the same way as tar --strip-components.
+func ExtractArchive(archive *File, format ArchiveFormat, dest string, strip int) ([]string, error) {
+	switch format {
+	case ARCHIVE_FORMAT_TAR_GZ, ARCHIVE_FORMAT_TAR:
+		h, err := archive.OpenFile()
+		if err != nil {
+			return nil, err
+		}
+		defer h.Close()
+
+		var r io.Reader = h
+		if format == ARCHIVE_FORMAT_TAR_GZ {
+			gz, err := gzip.NewReader(h)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+
+			r = gz
+		}
+
+		return ExtractTar(r, dest, strip)
+	case ARCHIVE_FORMAT_ZIP:
+		return extractZip(archive, dest, strip)
+	}
+
+	return nil, fmt.Errorf("Archive format is not supported: %s", format)
+}
+
+// ExtractTar extracts a tar stream into the destination directory and returns the extracted file paths relative to the destination.
+func ExtractTar(r io.Reader, dest string, strip int) ([]string, error) {
+	files := []string{}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		path, ok, err := archivePath(dest, header.Name, strip)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, header.FileInfo().Mode().Perm()|0700); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(path, tr, header.FileInfo().Mode().Perm()); err != nil {
+				return nil, err
+			}
+
+			rel, err := filepath.Rel(dest, path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, rel)
+		default:
+			// links and special files are not supported since they can point outside of the destination
+			continue
+		}
+	}
+
+	return files, nil
+}
+
+func extractZip(archive *File, dest string, strip int) ([]string, error) {
+	files := []string{}
+
+	zr, err := zip.OpenReader(archive.Abs())
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		path, ok, err := archivePath(dest, entry.Name, strip)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		mode := entry.Mode()
+
+		if mode.IsDir() {
+			if err := os.MkdirAll(path, mode.Perm()|0700); err != nil {
+				return nil, err
+			}
+
+			continue
+		} else if !mode.IsRegular() {
+			continue
+		}
+
+		err = func() error {
+			r, err := entry.Open()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			return writeArchiveFile(path, r, mode.Perm())
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := filepath.Rel(dest, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rel)
+	}
+
+	return files, nil
+}
+
+// archivePath resolves the path of an archive entry inside the destination, while guarding against path traversal.
+func archivePath(dest string, name string, strip int) (string, bool, error) {
+	parts := strings.Split(strings.Trim(filepath.ToSlash(name), "/"), "/")
+	if len(parts) <= strip {
+		return "", false, nil
+	}
+
+	path := filepath.Join(dest, filepath.Join(parts[strip:]...))
+	if rel, err := filepath.Rel(dest, path); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false, fmt.Errorf("Archive entry is outside of the destination: %s", name)
+	}
+
+	return path, true, nil
+}
+
+func writeArchiveFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if perm == 0 {
+		perm = 0644
+	}
+
+	h, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	//nolint:gosec
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+
+	return nil
+}
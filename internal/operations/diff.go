@@ -0,0 +1,132 @@
+package operations
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const diff_context_lines = 3
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// IsText checks whether the content looks like text, which is valid UTF-8 without any null bytes.
+func IsText(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// UnifiedDiff returns the line based difference between the contents in the unified format, it is empty when the contents are equal.
+func UnifiedDiff(fromName string, toName string, from []byte, to []byte) string {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(string(from), string(to))
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	all := []diffLine{}
+	changed := false
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+			changed = true
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+			changed = true
+		}
+
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line == "" {
+				continue
+			}
+
+			all = append(all, diffLine{op: op, text: line})
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+
+	// mark the changed lines with their context
+	include := make([]bool, len(all))
+	for i, line := range all {
+		if line.op == ' ' {
+			continue
+		}
+
+		for j := max(0, i-diff_context_lines); j <= min(len(all)-1, i+diff_context_lines); j++ {
+			include[j] = true
+		}
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", fromName, toName)
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(all); {
+		if !include[i] {
+			oldLine, newLine = advanceDiffLine(all[i], oldLine, newLine)
+			i++
+
+			continue
+		}
+
+		end := i
+		for end < len(all) && include[end] {
+			end++
+		}
+
+		oldStart, newStart := oldLine, newLine
+		hunk := &strings.Builder{}
+		for _, line := range all[i:end] {
+			hunk.WriteByte(line.op)
+			hunk.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				hunk.WriteString("\n\\ No newline at end of file\n")
+			}
+
+			oldLine, newLine = advanceDiffLine(line, oldLine, newLine)
+		}
+
+		fmt.Fprintf(
+			out,
+			"@@ -%s +%s @@\n%s",
+			diffRange(oldStart, oldLine-oldStart),
+			diffRange(newStart, newLine-newStart),
+			hunk.String(),
+		)
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func advanceDiffLine(line diffLine, oldLine int, newLine int) (int, int) {
+	switch line.op {
+	case '-':
+		return oldLine + 1, newLine
+	case '+':
+		return oldLine, newLine + 1
+	}
+
+	return oldLine + 1, newLine + 1
+}
+
+func diffRange(start int, count int) string {
+	if count == 0 {
+		start--
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
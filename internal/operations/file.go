@@ -2,9 +2,12 @@ package operations
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type File struct {
@@ -80,13 +83,10 @@ func (f *File) ReadLines() ([]string, error) {
 
 	scanner := bufio.NewScanner(h)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			lines = append(lines, line)
-		}
+		lines = append(lines, scanner.Text())
 	}
 
-	return lines, nil
+	return lines, scanner.Err()
 }
 
 func (f *File) OpenFile() (*os.File, error) {
@@ -109,6 +109,40 @@ func (f *File) WriteFile(data []byte, perm os.FileMode) error {
 	return os.WriteFile(f.Abs(), data, perm)
 }
 
+// WriteFileAtomic writes the data into a temporary file next to the file and renames it into place,
+// so that an interrupted write never leaves a truncated file behind.
+func (f *File) WriteFileAtomic(data []byte, perm os.FileMode) error {
+	temp, err := os.CreateTemp(f.cwd, fmt.Sprintf(".%s.*", f.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Chmod(perm); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), f.Abs())
+}
+
 func (f *File) Touch() error {
 	_, err := os.Create(f.Abs())
 
@@ -136,6 +170,15 @@ func (f *File) Chmod(perm os.FileMode) error {
 	return os.Chmod(f.Abs(), perm)
 }
 
+// Chown changes the owner of the file, where nil keeps the current one.
+func (f *File) Chown(owner *Owner) error {
+	if owner == nil {
+		return nil
+	}
+
+	return os.Lchown(f.Abs(), owner.Uid, owner.Gid)
+}
+
 func (f *File) CopyTo(target *File) error {
 	src, err := os.Open(f.Abs())
 	if err != nil {
@@ -157,6 +200,128 @@ func (f *File) CopyTo(target *File) error {
 	return target.MatchModeWith(f)
 }
 
+// CopyAtomicTo copies the file into a temporary file next to the target and renames it into place,
+// so that the target is never observed half-written, the owner is applied before the rename unless it is nil.
+func (f *File) CopyAtomicTo(target *File, owner *Owner) error {
+	ss, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(f.Abs())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	temp, err := os.CreateTemp(target.Cwd(), fmt.Sprintf(".%s.*", target.Rel()))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, src); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Chmod(ss.Mode()); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if owner != nil {
+		if err := temp.Chown(owner.Uid, owner.Gid); err != nil {
+			temp.Close()
+
+			return err
+		}
+	}
+
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), target.Abs())
+}
+
+// ReplaceDir fills a new directory next to the directory and swaps it into place only after it is filled successfully,
+// so that a failure keeps the previous content of the directory.
+func (f *File) ReplaceDir(perm os.FileMode, fill func(dir string) error) error {
+	if err := os.MkdirAll(f.cwd, 0755); err != nil {
+		return err
+	}
+
+	temp, err := os.MkdirTemp(f.cwd, fmt.Sprintf(".%s-", f.path))
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp, perm); err != nil {
+		os.RemoveAll(temp)
+
+		return err
+	}
+
+	if err := fill(temp); err != nil {
+		os.RemoveAll(temp)
+
+		return err
+	}
+
+	previous := fmt.Sprintf("%s.previous", temp)
+	if err := os.Rename(f.Abs(), previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+		os.RemoveAll(temp)
+
+		return err
+	}
+
+	if err := os.Rename(temp, f.Abs()); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(previous)
+}
+
+// Overlaps checks whether the file is the same as the other file or one of them contains the other, after resolving both
+// into absolute paths without symbolic links.
+func (f *File) Overlaps(other *File) bool {
+	a, b := f.resolve(), other.resolve()
+
+	return isWithin(a, b) || isWithin(b, a)
+}
+
+func (f *File) resolve() string {
+	path, err := filepath.Abs(f.Abs())
+	if err != nil {
+		return filepath.Clean(f.Abs())
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+
+	return path
+}
+
+// isWithin checks whether the path is the same as the parent or inside of it.
+func isWithin(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func (f *File) Mkdirp(perm os.FileMode) error {
 	return os.MkdirAll(f.Abs(), perm)
 }
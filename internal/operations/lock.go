@@ -1,7 +1,33 @@
 package operations
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrLockTimeout = errors.New("Timed out while waiting for the lock")
+
+const lockPollInterval = 500 * time.Millisecond
+
 type LockFile struct {
-	file *File
+	file       *File
+	handle     *os.File
+	timeout    time.Duration
+	staleAfter time.Duration
+	// refresh stops refreshing the timestamp of the holder while the lock is held.
+	refresh chan struct{}
+	mu      sync.Mutex
+}
+
+type LockFileHolder struct {
+	Pid       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 func NewLockFile(path ...string) *LockFile {
@@ -12,18 +38,283 @@ func NewLockFile(path ...string) *LockFile {
 	}
 }
 
+// SetTimeout sets the duration to wait for the lock, zero means waiting forever.
+func (f *LockFile) SetTimeout(timeout time.Duration) *LockFile {
+	f.timeout = timeout
+
+	return f
+}
+
+// SetStaleAfter sets the age after which a lock that has not been refreshed by its holder is considered stale and will be broken, zero disables it.
+func (f *LockFile) SetStaleAfter(staleAfter time.Duration) *LockFile {
+	f.staleAfter = staleAfter
+
+	return f
+}
+
+// Lock acquires an exclusive advisory lock on the file and blocks until it is acquired or the timeout is reached.
 func (f *LockFile) Lock() error {
-	return f.file.Touch()
+	var deadline time.Time
+	if f.timeout > 0 {
+		deadline = time.Now().Add(f.timeout)
+	}
+
+	for {
+		ok, err := f.TryLock()
+		if err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+
+		holder, err := f.Holder()
+		if err != nil {
+			return err
+		}
+
+		if f.staleAfter > 0 && holder.IsStale(f.staleAfter) {
+			// removing the file gives the next attempt a new inode, so the stale holder does not block us anymore
+			if err := f.file.Remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+
+			continue
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("%w: %s is held by %s", ErrLockTimeout, f.Path(), holder)
+		}
+
+		time.Sleep(lockPollInterval)
+	}
 }
 
+// TryLock tries to acquire the lock once without blocking.
+func (f *LockFile) TryLock() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.handle != nil {
+		return true, nil
+	}
+
+	if err := os.MkdirAll(f.file.Cwd(), 0755); err != nil {
+		return false, err
+	}
+
+	h, err := os.OpenFile(f.Path(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return false, err
+	}
+
+	if err := syscall.Flock(int(h.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); errors.Is(err, syscall.EWOULDBLOCK) {
+		h.Close()
+
+		return false, nil
+	} else if err != nil {
+		h.Close()
+
+		return false, err
+	}
+
+	// the file might have been removed or replaced by the previous holder while we were waiting for it
+	if ok, err := f.isSameFile(h); err != nil || !ok {
+		//nolint:errcheck
+		syscall.Flock(int(h.Fd()), syscall.LOCK_UN)
+		h.Close()
+
+		return false, err
+	}
+
+	if err := writeHolder(h); err != nil {
+		//nolint:errcheck
+		syscall.Flock(int(h.Fd()), syscall.LOCK_UN)
+		h.Close()
+
+		return false, err
+	}
+
+	f.handle = h
+
+	// the holder refreshes its timestamp, so that only the locks of the processes that stopped responding go stale
+	if f.staleAfter > 0 {
+		f.refresh = make(chan struct{})
+
+		go f.refreshHolder(h, f.refresh)
+	}
+
+	return true, nil
+}
+
+// refreshHolder rewrites the timestamp of the holder periodically until the lock is released.
+func (f *LockFile) refreshHolder(h *os.File, stop chan struct{}) {
+	ticker := time.NewTicker(f.staleAfter / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			if f.handle == h {
+				//nolint:errcheck
+				writeHolder(h)
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+func writeHolder(h *os.File) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	data, err := json.Marshal(&LockFileHolder{
+		Pid:       os.Getpid(),
+		Hostname:  hostname,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := h.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := h.WriteAt(data, 0); err != nil {
+		return err
+	}
+
+	return h.Sync()
+}
+
+// Unlock releases the lock if it is held by this process.
 func (f *LockFile) Unlock() error {
-	return f.file.Remove()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.handle == nil {
+		return nil
+	}
+
+	defer func() {
+		f.handle = nil
+	}()
+
+	if f.refresh != nil {
+		close(f.refresh)
+		f.refresh = nil
+	}
+
+	// remove the file before releasing the lock, so waiting processes notice that their handle is outdated
+	if ok, _ := f.isSameFile(f.handle); ok {
+		if err := f.file.Remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := syscall.Flock(int(f.handle.Fd()), syscall.LOCK_UN); err != nil {
+		f.handle.Close()
+
+		return err
+	}
+
+	return f.handle.Close()
 }
 
+// IsLocked returns whether the lock is currently held by this or any other process.
 func (f *LockFile) IsLocked() bool {
-	return f.file.Exists()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.handle != nil {
+		return true
+	}
+
+	h, err := os.Open(f.Path())
+	if err != nil {
+		return false
+	}
+	defer h.Close()
+
+	if err := syscall.Flock(int(h.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return errors.Is(err, syscall.EWOULDBLOCK)
+	}
+
+	//nolint:errcheck
+	syscall.Flock(int(h.Fd()), syscall.LOCK_UN)
+
+	return false
+}
+
+// Holder reads the information about the process that has written the lock file last.
+func (f *LockFile) Holder() (*LockFileHolder, error) {
+	if !f.file.Exists() {
+		return nil, nil
+	}
+
+	data, err := f.file.ReadFile()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	holder := &LockFileHolder{}
+	if err := json.Unmarshal(data, holder); err != nil {
+		//nolint:nilerr
+		return nil, nil
+	}
+
+	return holder, nil
 }
 
 func (f *LockFile) Path() string {
 	return f.file.Abs()
 }
+
+func (f *LockFile) isSameFile(h *os.File) (bool, error) {
+	hs, err := h.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	fs, err := f.file.Stat()
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(hs, fs), nil
+}
+
+// IsStale checks whether the holder has not refreshed the lock within the given age,
+// where a holder that is still running on the same host is never stale.
+func (h *LockFileHolder) IsStale(staleAfter time.Duration) bool {
+	if h == nil || time.Since(h.Timestamp) <= staleAfter {
+		return false
+	}
+
+	if hostname, err := os.Hostname(); err == nil && hostname == h.Hostname {
+		return errors.Is(syscall.Kill(h.Pid, 0), syscall.ESRCH)
+	}
+
+	return true
+}
+
+func (h *LockFileHolder) String() string {
+	if h == nil {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("pid %d on %s refreshed at %s", h.Pid, h.Hostname, h.Timestamp.Format(time.RFC3339))
+}
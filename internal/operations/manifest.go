@@ -0,0 +1,138 @@
+package operations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type ManifestEntry struct {
+	Hash string      `json:"hash"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// Manifest describes the content of a directory with paths relative to its root.
+type Manifest map[string]ManifestEntry
+
+// NewManifest walks the root directory and hashes every file in it, symbolic links to files are followed.
+// Entries starting with ".." are skipped, since those are the internals of atomically updated Kubernetes volumes.
+func NewManifest(root string) (Manifest, error) {
+	manifest := Manifest{}
+
+	err := filepath.WalkDir(root, func(abs string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return fmt.Errorf("Error walking: %s -> %w", abs, e)
+		} else if abs == root {
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), "..") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		} else if d.IsDir() {
+			return nil
+		}
+
+		stat, err := os.Stat(abs)
+		if err != nil {
+			return err
+		} else if !stat.Mode().IsRegular() {
+			return nil
+		}
+
+		hash, err := hashFile(abs)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return err
+		}
+
+		manifest[rel] = ManifestEntry{
+			Hash: hash,
+			Mode: stat.Mode().Perm(),
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Changed returns the paths that are added or modified in the next manifest.
+func (m Manifest) Changed(next Manifest) []string {
+	changed := []string{}
+
+	for path, entry := range next {
+		if previous, ok := m[path]; !ok || previous != entry {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+
+	return changed
+}
+
+// Removed returns the paths that do not exist in the next manifest anymore.
+func (m Manifest) Removed(next Manifest) []string {
+	removed := []string{}
+
+	for path := range m {
+		if _, ok := next[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	slices.Sort(removed)
+
+	return removed
+}
+
+// Equal checks whether both manifests describe the same content.
+func (m Manifest) Equal(other Manifest) bool {
+	return len(m.Changed(other)) == 0 && len(m.Removed(other)) == 0
+}
+
+// Digest returns a single hash that identifies the content of the manifest.
+func (m Manifest) Digest() string {
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s %o %s\n", m[path].Hash, m[path].Mode, path)
+	}
+
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(h.Sum(nil)))
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Owner is the user and the group that the files are owned by, where -1 keeps the current one.
+type Owner struct {
+	Uid int
+	Gid int
+}
+
+// ParseOwner parses the owner in the format of user[:group], where both can be a name or a numeric id.
+func ParseOwner(value string) (*Owner, error) {
+	name, group, _ := strings.Cut(value, ":")
+
+	owner := &Owner{Uid: -1, Gid: -1}
+
+	if name != "" {
+		uid, err := strconv.Atoi(name)
+		if err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return nil, fmt.Errorf("Can not find the user of the owner: %s -> %w", value, err)
+			}
+
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return nil, err
+			}
+		}
+
+		owner.Uid = uid
+	}
+
+	if group != "" {
+		gid, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return nil, fmt.Errorf("Can not find the group of the owner: %s -> %w", value, err)
+			}
+
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return nil, err
+			}
+		}
+
+		owner.Gid = gid
+	}
+
+	if owner.Uid < 0 && owner.Gid < 0 {
+		return nil, fmt.Errorf("Owner is not valid, expected user[:group]: %s", value)
+	}
+
+	return owner, nil
+}
+
+func (o *Owner) String() string {
+	return fmt.Sprintf("%d:%d", o.Uid, o.Gid)
+}
+
+// Matches checks whether the file info is owned by the owner, where the missing user or group always matches.
+func (o *Owner) Matches(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+
+	return (o.Uid < 0 || int(stat.Uid) == o.Uid) && (o.Gid < 0 || int(stat.Gid) == o.Gid)
+}
@@ -2,25 +2,101 @@ package internal
 
 import (
 	"github.com/sirupsen/logrus"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
 )
 
 type ServiceCtx struct {
 	Log              *logrus.Entry
-	State            *State
 	WorkingDirectory string
-	RootDirectory    string
-	TargetDirectory  string
+	Mappings         []*Mapping
 	Flags            *ServiceFlags
+	Changes          *Changes
+	// Provides checks whether the file relative to the working directory is still provided by another layer of the source.
+	Provides func(file string) bool
 }
 
 type ServiceFlags struct {
 	WorkingDirectory string
-	TargetDirectory  string `validate:"required"`
+	TargetDirectory  string
 	RootDirectory    string
 
 	ForceSync                  bool
 	SyncDelete                 bool
 	SyncDeleteEmptyDirectories bool
+	DryRun                     bool
 
 	TemplateFiles []string
 }
+
+// Mapping syncs a root directory of the source into a target directory with its own rules and state.
+type Mapping struct {
+	Name            string
+	RootDirectory   string
+	TargetDirectory string
+	StateFile       string
+	IgnoreFile      string
+	Include         []string
+	Exclude         []string
+	TemplateFiles   []string
+	TemplateValues  []string
+	// Owner of the applied files and directories, nil keeps the owner of the process.
+	Owner                      *operations.Owner
+	SyncDelete                 bool
+	SyncDeleteEmptyDirectories bool
+
+	State *State
+}
+
+func (m *Mapping) String() string {
+	return m.Name
+}
+
+// Layer returns the context for a layer of the source in its own working directory,
+// where the mappings keep the state of the layer next to the others.
+func (c *ServiceCtx) Layer(name string, workingDirectory string) *ServiceCtx {
+	ctx := &ServiceCtx{
+		Log:              c.Log.WithField("layer", name),
+		WorkingDirectory: workingDirectory,
+		Mappings:         []*Mapping{},
+		Flags:            c.Flags,
+		Changes:          c.Changes,
+	}
+
+	for _, m := range c.Mappings {
+		layer := *m
+		layer.State = m.State.Layer(name)
+
+		ctx.Mappings = append(ctx.Mappings, &layer)
+	}
+
+	return ctx
+}
+
+// SetDirty marks the states of all the mappings as dirty, since they share the same source.
+func (c *ServiceCtx) SetDirty() {
+	for _, m := range c.Mappings {
+		m.State.SetDirty()
+	}
+}
+
+// IsDirty checks whether any of the mappings has to be applied.
+func (c *ServiceCtx) IsDirty() bool {
+	for _, m := range c.Mappings {
+		if m.State.IsDirty() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pinned returns the pin of the first pinned mapping, since the mappings are rolled back together.
+func (c *ServiceCtx) Pinned() *StatePin {
+	for _, m := range c.Mappings {
+		if pin := m.State.Pinned(); pin != nil {
+			return pin
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"gitlab.kilic.dev/docker/beamer/internal/operations"
 	. "gitlab.kilic.dev/libraries/plumber/v5"
@@ -11,6 +15,48 @@ type State struct {
 	file  string
 	dirty bool
 	log   *logrus.Entry
+
+	historyLimit int
+	history      []StateHistory
+	pin          *StatePin
+	pinChanged   bool
+
+	// layer is the name of the layer that owns the state of its adapter, where the history and the pin belong to the parent.
+	layer  string
+	parent *State
+}
+
+// StateHistory is a revision that was applied to the target directory.
+type StateHistory struct {
+	Revision  string    `json:"revision"`
+	Adapter   string    `json:"adapter"`
+	AppliedAt time.Time `json:"appliedAt"`
+	Snapshot  string    `json:"snapshot,omitempty"`
+	// Files is the manifest of the revision, which is only kept for the latest and the pinned revision since only those are compared to the target directory.
+	Files operations.Manifest `json:"files,omitempty"`
+}
+
+// StatePin is the revision that the target directory is rolled back to, which is kept until it is unpinned.
+type StatePin struct {
+	Revision string    `json:"revision"`
+	Snapshot string    `json:"snapshot,omitempty"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}
+
+func (p *StatePin) String() string {
+	if p.Snapshot != "" {
+		return fmt.Sprintf("%s (snapshot %s)", p.Revision, p.Snapshot)
+	}
+
+	return p.Revision
+}
+
+// stateFile is the content of the state file, where the adapter owns its own state.
+type stateFile struct {
+	Adapter json.RawMessage            `json:"adapter"`
+	Layers  map[string]json.RawMessage `json:"layers,omitempty"`
+	History []StateHistory             `json:"history,omitempty"`
+	Pin     *StatePin                  `json:"pin,omitempty"`
 }
 
 func NewState(ctx *ServiceCtx, file string) *State {
@@ -21,33 +67,217 @@ func NewState(ctx *ServiceCtx, file string) *State {
 	}
 }
 
+// Layer returns the state for the adapter of a layer, which is kept in the same state file.
+//
+// Marking the layer dirty marks the parent dirty as well, while the layer is only cleaned by itself.
+func (s *State) Layer(name string) *State {
+	return &State{
+		ctx:    s.ctx,
+		file:   s.file,
+		log:    s.log.WithField("layer", name),
+		layer:  name,
+		parent: s,
+	}
+}
+
+// SetHistoryLimit sets the number of applied revisions that are kept in the history.
+func (s *State) SetHistoryLimit(limit int) *State {
+	s.historyLimit = limit
+
+	return s
+}
+
+// Read returns the state of the adapter.
 func (s *State) Read() ([]byte, error) {
-	s.log.Debugf("Reading state: %s", s.file)
+	state, err := s.load()
+	if err != nil || state == nil {
+		return nil, err
+	}
 
-	f := operations.NewFile(s.file)
-	if !f.Exists() {
+	data := state.Adapter
+	if s.layer != "" {
+		data = state.Layers[s.layer]
+	}
+
+	if len(data) == 0 || string(data) == "null" {
 		return nil, nil
 	}
 
-	return f.ReadFile()
+	return data, nil
 }
 
+// Write replaces the state of the adapter and commits the pending history and pin with it.
 func (s *State) Write(data []byte) error {
-	s.log.Debugf("Writing state: %s", s.file)
+	return s.commit(data)
+}
 
-	f := operations.NewFile(s.file)
+// Flush commits the pending history and pin while keeping the state of the adapter.
+func (s *State) Flush() error {
+	return s.commit(nil)
+}
+
+// History returns the applied revisions from the oldest to the latest.
+func (s *State) History() ([]StateHistory, error) {
+	state, err := s.load()
+	if err != nil || state == nil {
+		return nil, err
+	}
+
+	return state.History, nil
+}
+
+// AddHistory records an applied revision, which is committed with the next write.
+func (s *State) AddHistory(entry StateHistory) {
+	s.history = append(s.history, entry)
+}
+
+// Refresh reads the pin from the state file, since it might be changed by another process, and returns whether it has changed.
+func (s *State) Refresh() (bool, error) {
+	state, err := s.load()
+	if err != nil {
+		return false, err
+	}
+
+	var pin *StatePin
+	if state != nil {
+		pin = state.Pin
+	}
+
+	changed := (pin == nil) != (s.pin == nil) || (pin != nil && *pin != *s.pin)
+	s.pin = pin
+	s.pinChanged = false
+
+	return changed, nil
+}
+
+// Pinned returns the pinned revision, the layers always follow their own sources since only the combined revision is pinned.
+func (s *State) Pinned() *StatePin {
+	if s.layer != "" {
+		return nil
+	}
+
+	return s.pin
+}
 
-	return f.WriteFile(data, 0600)
+// SetPin pins the target directory to the given revision or unpins it with nil, which is committed with the next write.
+func (s *State) SetPin(pin *StatePin) {
+	s.pin = pin
+	s.pinChanged = true
 }
 
 func (s *State) SetDirty() {
 	s.dirty = true
+
+	if s.parent != nil {
+		s.parent.SetDirty()
+	}
 }
 
 func (s *State) IsDirty() bool {
-	return s.dirty
+	return s.dirty || (s.parent != nil && s.parent.IsDirty())
 }
 
 func (s *State) SetClean() {
 	s.dirty = false
 }
+
+func (s *State) load() (*stateFile, error) {
+	s.log.Debugf("Reading state: %s", s.file)
+
+	f := operations.NewFile(s.file)
+	if !f.Exists() {
+		return nil, nil
+	}
+
+	data, err := f.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("Can not parse the state file: %s -> %w", s.file, err)
+	}
+
+	// state files of the previous versions only contain the state of the adapter
+	if _, ok := fields["adapter"]; !ok {
+		return &stateFile{Adapter: data}, nil
+	}
+
+	state := &stateFile{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("Can not parse the state file: %s -> %w", s.file, err)
+	}
+
+	return state, nil
+}
+
+func (s *State) commit(adapter []byte) error {
+	if s.ctx.Flags.DryRun {
+		s.log.Debugf("Skipping writing state in dry run: %s", s.file)
+
+		return nil
+	}
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	} else if state == nil {
+		state = &stateFile{}
+	}
+
+	switch {
+	case adapter != nil && s.layer != "":
+		if state.Layers == nil {
+			state.Layers = map[string]json.RawMessage{}
+		}
+
+		state.Layers[s.layer] = adapter
+	case adapter != nil:
+		state.Adapter = adapter
+	}
+
+	if s.pinChanged {
+		state.Pin = s.pin
+	}
+
+	for _, entry := range s.history {
+		// the same revision might be applied again, e.g. with a forced workflow
+		if l := len(state.History); l > 0 && state.History[l-1].Revision == entry.Revision {
+			state.History[l-1] = entry
+
+			continue
+		}
+
+		state.History = append(state.History, entry)
+	}
+
+	if s.historyLimit > 0 && len(state.History) > s.historyLimit {
+		state.History = state.History[len(state.History)-s.historyLimit:]
+	}
+
+	for i := range state.History {
+		if i == len(state.History)-1 || (state.Pin != nil && state.History[i].Revision == state.Pin.Revision) {
+			continue
+		}
+
+		state.History[i].Files = nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debugf("Writing state: %s", s.file)
+
+	// the state is written atomically, since a truncated state file loses the applied revision and the history
+	if err := operations.NewFile(s.file).WriteFileAtomic(data, 0600); err != nil {
+		return err
+	}
+
+	s.history = nil
+	s.pinChanged = false
+
+	return nil
+}
@@ -20,6 +20,7 @@ func main() {
 						pipe.New(p).SetCliContext(ctx).Job(),
 					)
 				},
+				Commands: commands(p),
 			}
 		}).
 		SetDocumentationOptions(DocumentationOptions{
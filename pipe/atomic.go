@@ -0,0 +1,251 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// createStagingDirectory creates the directory that the revision is rendered into.
+//
+// The revisions are swapped with a rename, so the staging directory has to live next to them on the same filesystem,
+// while the dry runs never swap and must not touch the target directory.
+func createStagingDirectory(t *Task[Pipe], m *internal.Mapping) (string, error) {
+	if t.Pipe.Config.Atomic != ATOMIC_SWAP || t.Pipe.DryRun {
+		return os.MkdirTemp("", "beamer-staging-")
+	}
+
+	revisions := filepath.Join(m.TargetDirectory, ATOMIC_REVISIONS_DIRECTORY)
+	if err := os.MkdirAll(revisions, 0755); err != nil {
+		return "", err
+	}
+
+	return os.MkdirTemp(revisions, ATOMIC_STAGING_PREFIX)
+}
+
+// currentDirectory returns the directory that holds the currently applied files of the mapping.
+func currentDirectory(t *Task[Pipe], m *internal.Mapping) string {
+	if t.Pipe.Config.Atomic == ATOMIC_SWAP {
+		return filepath.Join(m.TargetDirectory, ATOMIC_CURRENT_LINK)
+	}
+
+	return m.TargetDirectory
+}
+
+// shouldWrite checks whether the files are written to the target directory one by one.
+func shouldWrite(t *Task[Pipe]) bool {
+	return !t.Pipe.DryRun && t.Pipe.Config.Atomic != ATOMIC_SWAP
+}
+
+// stageDirectoryModes copies the modes of the source directories to the staging directory,
+// which are created with restricted permissions while rendering, together with the owner of the mapping.
+func stageDirectoryModes(t *Task[Pipe], m *internal.Mapping, staging string, files []string) error {
+	root := operations.NewFile(t.Pipe.WorkingDirectory, m.RootDirectory)
+	stat, err := root.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(staging, stat.Mode().Perm()); err != nil {
+		return err
+	}
+
+	dirs := []string{}
+	for _, path := range files {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
+
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(m.RootDirectory, fmt.Sprintf("/%s", dir))
+		if err != nil {
+			return err
+		}
+
+		stat, err := operations.NewFile(t.Pipe.WorkingDirectory, dir).Stat()
+		if err != nil {
+			return err
+		}
+
+		if err := os.Chmod(filepath.Join(staging, rel), stat.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	if m.Owner == nil {
+		return nil
+	}
+
+	return filepath.WalkDir(staging, func(abs string, _ fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		return operations.NewFile(abs).Chown(m.Owner)
+	})
+}
+
+// recordRemovedFiles records the files of the current revision that do not exist in the next one, since they disappear with the swap.
+func recordRemovedFiles(t *Task[Pipe], m *internal.Mapping, rendered []string) error {
+	current := currentDirectory(t, m)
+	if _, err := os.Stat(current); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return filepath.WalkDir(current+string(filepath.Separator), func(abs string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return fmt.Errorf("Error walking: %s -> %w", abs, e)
+		} else if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(current, abs)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(rendered, rel) {
+			t.Pipe.Ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_DELETE,
+				Path:   abs,
+			})
+		}
+
+		return nil
+	})
+}
+
+// hasChanges checks whether any of the files or directories in the target of the mapping differ from the staged ones.
+func hasChanges(t *Task[Pipe], m *internal.Mapping) bool {
+	current := currentDirectory(t, m) + string(filepath.Separator)
+
+	for _, change := range t.Pipe.Ctx.Changes.List() {
+		if !strings.HasPrefix(change.Path, current) {
+			continue
+		}
+
+		switch change.Action {
+		case internal.CHANGE_ACTION_CREATE,
+			internal.CHANGE_ACTION_UPDATE,
+			internal.CHANGE_ACTION_CHMOD,
+			internal.CHANGE_ACTION_DELETE,
+			internal.CHANGE_ACTION_MKDIR:
+			return true
+		}
+	}
+
+	return false
+}
+
+// swapRevision moves the staging directory to a new revision and points the current link to it, returning the name of the revision.
+func swapRevision(t *Task[Pipe], m *internal.Mapping, staging string) (string, error) {
+	revisions := filepath.Dir(staging)
+	name := time.Now().UTC().Format("20060102T150405.000000000Z")
+
+	if err := os.Rename(staging, filepath.Join(revisions, name)); err != nil {
+		return "", err
+	}
+
+	if err := pointCurrentTo(t, m, name); err != nil {
+		return "", err
+	}
+
+	return name, pruneRevisions(t, revisions, name)
+}
+
+// pointCurrentTo switches the current link to the given revision in a single rename.
+func pointCurrentTo(t *Task[Pipe], m *internal.Mapping, name string) error {
+	link := currentDirectory(t, m)
+
+	if stat, err := os.Lstat(link); err == nil && stat.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("Current revision is not a symlink, can not swap: %s", link)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	temp := filepath.Join(m.TargetDirectory, fmt.Sprintf(".%s.%s", ATOMIC_CURRENT_LINK, name))
+	if err := os.Symlink(filepath.Join(ATOMIC_REVISIONS_DIRECTORY, name), temp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temp, link); err != nil {
+		os.Remove(temp)
+
+		return err
+	}
+
+	t.Log.Infof("Swapped the current revision: %s -> %s", link, name)
+
+	return nil
+}
+
+// currentRevision returns the name of the revision that the current link points to.
+func currentRevision(t *Task[Pipe], m *internal.Mapping) (string, error) {
+	target, err := os.Readlink(currentDirectory(t, m))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return filepath.Base(target), nil
+}
+
+// snapshotDirectory returns the directory of a previous revision, which is empty when it is not available anymore.
+func snapshotDirectory(t *Task[Pipe], m *internal.Mapping, name string) string {
+	if t.Pipe.Config.Atomic != ATOMIC_SWAP || name == "" {
+		return ""
+	}
+
+	dir := filepath.Join(m.TargetDirectory, ATOMIC_REVISIONS_DIRECTORY, name)
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return ""
+	}
+
+	return dir
+}
+
+// pruneRevisions removes the revisions that are older than the number of revisions to keep.
+func pruneRevisions(t *Task[Pipe], revisions string, current string) error {
+	entries, err := os.ReadDir(revisions)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ATOMIC_STAGING_PREFIX) || entry.Name() == current {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+	// the names of the revisions are ordered by their creation time
+	slices.Sort(names)
+
+	if len(names) <= t.Pipe.Config.AtomicRevisions {
+		return nil
+	}
+
+	for _, name := range names[:len(names)-t.Pipe.Config.AtomicRevisions] {
+		path := filepath.Join(revisions, name)
+
+		t.Log.Debugf("Removing previous revision: %s", path)
+
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
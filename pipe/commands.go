@@ -0,0 +1,66 @@
+package pipe
+
+import (
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// NewDiff prints the changes between the source and the target directory without touching the target.
+func NewDiff(p *Plumber) *TaskList[Pipe] {
+	tl := New(p)
+	tl.Pipe.DryRun = true
+
+	return tl
+}
+
+// NewStatus prints the state of the target directory.
+func NewStatus(p *Plumber) *TaskList[Pipe] {
+	return TL.New(p).
+		SetRuntimeDepth(2).
+		ShouldRunBefore(setup).
+		Set(func(tl *TaskList[Pipe]) Job {
+			return Status(tl).Job()
+		})
+}
+
+// NewRender prints a single template that is rendered with the current revision of the source.
+func NewRender(p *Plumber) *TaskList[Pipe] {
+	return TL.New(p).
+		SetRuntimeDepth(2).
+		ShouldRunBefore(func(tl *TaskList[Pipe]) error {
+			tl.Pipe.Config.RenderFile = tl.CliContext.Args().First()
+			// only the source is required, the target directory is not touched
+			tl.Pipe.DryRun = true
+
+			return setup(tl)
+		}).
+		Set(func(tl *TaskList[Pipe]) Job {
+			return tl.JobSequence(
+				a.Init(),
+				Render(tl).Job(),
+			)
+		})
+}
+
+// NewValidate checks the configuration, the access to the source and renders all the templates with the validation hooks without touching the target.
+func NewValidate(p *Plumber) *TaskList[Pipe] {
+	return TL.New(p).
+		SetRuntimeDepth(2).
+		ShouldRunBefore(func(tl *TaskList[Pipe]) error {
+			tl.Pipe.DryRun = true
+
+			return setup(tl)
+		}).
+		Set(func(tl *TaskList[Pipe]) Job {
+			return tl.JobSequence(
+				a.Init(),
+				Workflow(tl).Job(),
+				tl.CreateTask("validate").
+					Set(func(t *Task[Pipe]) error {
+						t.Log.Infof("Configuration, source and templates are valid.")
+
+						return nil
+					}).
+					Job(),
+			)
+		})
+}
@@ -0,0 +1,360 @@
+package pipe
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v2"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CONFIG_FILE_MAPPINGS = "mappings"
+	CONFIG_FILE_LAYERS   = "layers"
+)
+
+var configFileInterpolation = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// loadConfigFile sets the flags from the configuration file, which are not already set with the flags or the environment variables.
+func loadConfigFile(tl *TaskList[Pipe]) error {
+	path := tl.CliContext.String("config")
+	if path == "" {
+		return nil
+	}
+
+	f := operations.NewFile(path)
+	if !f.IsFile() {
+		return fmt.Errorf("Config file does not exist: %s", f.Abs())
+	}
+
+	data, err := f.ReadFile()
+	if err != nil {
+		return err
+	}
+
+	parsed := map[string]any{}
+	switch strings.ToLower(f.Ext()) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &parsed)
+	case ".toml":
+		err = toml.Unmarshal(data, &parsed)
+	default:
+		return fmt.Errorf("Config file format is not supported, expected YAML or TOML: %s", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Can not parse the config file: %s -> %w", path, err)
+	}
+
+	options := map[string][]string{}
+	if err := flattenConfigFile("", parsed, options); err != nil {
+		return fmt.Errorf("Config file is not valid: %s -> %w", path, err)
+	}
+
+	names := configFileFlagNames()
+
+	for key, values := range options {
+		name, ok := names[key]
+		if !ok {
+			return fmt.Errorf("Config file option is not known: %s -> %s", path, key)
+		}
+
+		// the flag does not belong to the current command
+		if tl.CliContext.Generic(name) == nil {
+			continue
+		}
+
+		// the flags and the environment variables take precedence over the file
+		if tl.CliContext.IsSet(name) {
+			tl.Log.Debugf("Config file option is overridden by the flag or the environment variable: %s", key)
+
+			continue
+		}
+
+		if err := setConfigFileFlag(tl.CliContext, name, values); err != nil {
+			return fmt.Errorf("Config file option is not valid: %s -> %s -> %w", path, key, err)
+		}
+	}
+
+	tl.Log.Debugf("Loaded config file: %s", f.Abs())
+
+	return nil
+}
+
+// configFileFlagNames returns the names of the flags, which also resolves the aliases to their names.
+func configFileFlagNames() map[string]string {
+	names := map[string]string{}
+
+	for _, flag := range slices.Concat(Flags, SyncFlags, RollbackFlags) {
+		for _, name := range flag.Names() {
+			if _, ok := names[name]; !ok {
+				names[name] = flag.Names()[0]
+			}
+		}
+	}
+
+	return names
+}
+
+// setConfigFileFlag sets the value of the flag, where the lists replace the slice as a whole instead of being split by commas.
+func setConfigFileFlag(ctx *cli.Context, name string, values []string) error {
+	if slice, ok := ctx.Generic(name).(*cli.StringSlice); ok {
+		*slice = *cli.NewStringSlice(values...)
+
+		return nil
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("Option does not accept a list: %s", name)
+	}
+
+	return ctx.Set(name, values[0])
+}
+
+// flattenConfigFile flattens the nested tables of the configuration file into the flag names joined with dashes,
+// e.g. hook.commands sets hook-commands, while the structured mappings and layers are converted into the format of their flags.
+func flattenConfigFile(prefix string, values map[string]any, options map[string][]string) error {
+	for key, value := range values {
+		name := key
+		if prefix != "" {
+			name = prefix + "-" + key
+		}
+
+		switch {
+		case name == CONFIG_FILE_MAPPINGS:
+			mappings, err := configFileList(name, value, configFileMapping)
+			if err != nil {
+				return err
+			}
+
+			options["mapping"] = append(options["mapping"], mappings...)
+
+			continue
+		case name == CONFIG_FILE_LAYERS:
+			layers, err := configFileList(name, value, configFileLayer)
+			if err != nil {
+				return err
+			}
+
+			options["layer"] = append(options["layer"], layers...)
+
+			continue
+		}
+
+		if table, ok := value.(map[string]any); ok {
+			if err := flattenConfigFile(name, table, options); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		parsed, err := configFileValues(name, value)
+		if err != nil {
+			return err
+		}
+
+		options[name] = append(options[name], parsed...)
+	}
+
+	return nil
+}
+
+// configFileList converts the entries of a structured list, where the entries can also be given in the format of the flag.
+func configFileList(name string, value any, convert func(entry map[string]any) (string, error)) ([]string, error) {
+	var entries []any
+
+	switch v := value.(type) {
+	case []any:
+		entries = v
+	// the arrays of tables are decoded with their own type from TOML
+	case []map[string]any:
+		for _, entry := range v {
+			entries = append(entries, entry)
+		}
+	default:
+		return nil, fmt.Errorf("Option should be a list: %s", name)
+	}
+
+	converted := []string{}
+	for i, entry := range entries {
+		var result string
+		var err error
+
+		if table, ok := entry.(map[string]any); ok {
+			result, err = convert(table)
+		} else {
+			result, err = configFileScalar(fmt.Sprintf("%s.%d", name, i), entry)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s.%d -> %w", name, i, err)
+		}
+
+		converted = append(converted, result)
+	}
+
+	return converted, nil
+}
+
+// configFileMapping converts a mapping to the format of root:target[;option=value].
+func configFileMapping(entry map[string]any) (string, error) {
+	root, err := configFileScalar("root", entry["root"])
+	if err != nil {
+		return "", err
+	}
+
+	target, err := configFileScalar("target", entry["target"])
+	if err != nil {
+		return "", err
+	} else if target == "" {
+		return "", fmt.Errorf("Mapping requires a target")
+	}
+
+	if root == "" {
+		root = "/"
+	}
+
+	options := map[string][]string{}
+	for key, value := range entry {
+		if key == "root" || key == "target" {
+			continue
+		}
+
+		parsed, err := configFileValues(key, value)
+		if err != nil {
+			return "", err
+		}
+		options[key] = parsed
+	}
+
+	return configFileOptions(root+":"+target, options)
+}
+
+// configFileLayer converts a layer to the format of adapter[;name=name][;option=value], where the options of the adapter can also be nested under its name.
+func configFileLayer(entry map[string]any) (string, error) {
+	adapter, err := configFileScalar("adapter", entry["adapter"])
+	if err != nil {
+		return "", err
+	} else if adapter == "" {
+		return "", fmt.Errorf("Layer requires an adapter")
+	}
+
+	options := map[string][]string{}
+	for key, value := range entry {
+		if key == "adapter" {
+			continue
+		}
+
+		if table, ok := value.(map[string]any); ok {
+			if err := flattenConfigFile(key, table, options); err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		parsed, err := configFileValues(key, value)
+		if err != nil {
+			return "", err
+		}
+		options[key] = parsed
+	}
+
+	return configFileOptions(adapter, options)
+}
+
+// configFileOptions appends the options in a stable order to the value in the format of the flags.
+func configFileOptions(value string, options map[string][]string) (string, error) {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	// the name comes first for the readability of the logs
+	if i := slices.Index(keys, "name"); i > 0 {
+		keys = slices.Insert(slices.Delete(keys, i, i+1), 0, "name")
+	}
+
+	for _, key := range keys {
+		for _, option := range options[key] {
+			if strings.Contains(option, ";") {
+				return "", fmt.Errorf("Option can not contain a semicolon: %s", key)
+			}
+
+			value += fmt.Sprintf(";%s=%s", key, option)
+		}
+	}
+
+	return value, nil
+}
+
+// configFileValues converts a scalar or a list of scalars into the values of a flag.
+func configFileValues(name string, value any) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		parsed, err := configFileScalar(name, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{parsed}, nil
+	}
+
+	values := []string{}
+	for i, entry := range list {
+		parsed, err := configFileScalar(fmt.Sprintf("%s.%d", name, i), entry)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, parsed)
+	}
+
+	return values, nil
+}
+
+// configFileScalar converts a scalar into the value of a flag, where the environment variables are interpolated in the strings.
+func configFileScalar(name string, value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return interpolateConfigFile(name, v)
+	case map[string]any, []any, []map[string]any:
+		return "", fmt.Errorf("Option should be a scalar: %s", name)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// interpolateConfigFile replaces ${NAME} and ${NAME:-default} with the environment variables, where $$ escapes the dollar sign.
+func interpolateConfigFile(name string, value string) (string, error) {
+	var err error
+
+	interpolated := configFileInterpolation.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := configFileInterpolation.FindStringSubmatch(match)
+		if env, ok := os.LookupEnv(groups[1]); ok {
+			return env
+		} else if groups[2] != "" {
+			return groups[3]
+		}
+
+		err = fmt.Errorf("Environment variable is not set for the option: %s -> %s", name, groups[1])
+
+		return match
+	})
+
+	return interpolated, err
+}
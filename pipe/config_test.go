@@ -0,0 +1,259 @@
+package pipe
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestInterpolateConfigFile(t *testing.T) {
+	t.Setenv("BEAMER_TEST_VALUE", "value")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      bool
+	}{
+		{name: "plain", value: "plain", expected: "plain"},
+		{name: "variable", value: "a-${BEAMER_TEST_VALUE}-b", expected: "a-value-b"},
+		{name: "default is not used when set", value: "${BEAMER_TEST_VALUE:-other}", expected: "value"},
+		{name: "default", value: "${BEAMER_TEST_UNSET:-other}", expected: "other"},
+		{name: "empty default", value: "${BEAMER_TEST_UNSET:-}", expected: ""},
+		{name: "escaped dollar", value: "$$", expected: "$"},
+		{name: "escaped variable", value: "$${BEAMER_TEST_VALUE}", expected: "${BEAMER_TEST_VALUE}"},
+		{name: "shell variable is kept", value: "echo $BEAMER_CHANGED_FILES", expected: "echo $BEAMER_CHANGED_FILES"},
+		{name: "unset", value: "${BEAMER_TEST_UNSET}", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := interpolateConfigFile("option", test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %q", result)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestConfigFileMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    map[string]any
+		expected string
+		err      bool
+	}{
+		{
+			name:     "root and target",
+			entry:    map[string]any{"root": "nginx", "target": "/etc/nginx"},
+			expected: "nginx:/etc/nginx",
+		},
+		{
+			name:     "root defaults to the source",
+			entry:    map[string]any{"target": "/etc/app"},
+			expected: "/:/etc/app",
+		},
+		{
+			name: "options are sorted with the name first",
+			entry: map[string]any{
+				"root":        "nginx",
+				"target":      "/etc/nginx",
+				"sync-delete": true,
+				"owner":       "nginx:nginx",
+				"name":        "web",
+				"include":     []any{"**/*.conf", "mime.types"},
+			},
+			expected: "nginx:/etc/nginx;name=web;include=**/*.conf;include=mime.types;owner=nginx:nginx;sync-delete=true",
+		},
+		{
+			name:  "target is required",
+			entry: map[string]any{"root": "nginx"},
+			err:   true,
+		},
+		{
+			name:  "semicolon in an option",
+			entry: map[string]any{"target": "/etc/app", "include": "a;b"},
+			err:   true,
+		},
+		{
+			name:  "nested table in an option",
+			entry: map[string]any{"target": "/etc/app", "include": map[string]any{"a": "b"}},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := configFileMapping(test.entry)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %q", result)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestConfigFileLayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    map[string]any
+		expected string
+		err      bool
+	}{
+		{
+			name:     "adapter",
+			entry:    map[string]any{"adapter": "local", "local-directory": "/overlay"},
+			expected: "local;local-directory=/overlay",
+		},
+		{
+			name: "options nested under the adapter",
+			entry: map[string]any{
+				"adapter": "git",
+				"name":    "base",
+				"git":     map[string]any{"repository": "https://example.com/config.git", "ref": "main"},
+			},
+			expected: "git;name=base;git-ref=main;git-repository=https://example.com/config.git",
+		},
+		{
+			name: "list options",
+			entry: map[string]any{
+				"adapter": "http",
+				"http":    map[string]any{"headers": []any{"A: 1, 2", "B: 3"}},
+			},
+			expected: "http;http-headers=A: 1, 2;http-headers=B: 3",
+		},
+		{
+			name:  "adapter is required",
+			entry: map[string]any{"name": "base"},
+			err:   true,
+		},
+		{
+			name:  "semicolon in an option",
+			entry: map[string]any{"adapter": "local", "local-directory": "/a;b"},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := configFileLayer(test.entry)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %q", result)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestFlattenConfigFile(t *testing.T) {
+	t.Setenv("BEAMER_TEST_REF", "main")
+
+	tests := []struct {
+		name     string
+		values   map[string]any
+		expected map[string][]string
+		err      bool
+	}{
+		{
+			name:     "scalars",
+			values:   map[string]any{"adapter": "git", "once": true, "history": 5},
+			expected: map[string][]string{"adapter": {"git"}, "once": {"true"}, "history": {"5"}},
+		},
+		{
+			name:     "nested tables",
+			values:   map[string]any{"git": map[string]any{"ref": "${BEAMER_TEST_REF}"}, "hook": map[string]any{"commands": []any{"a, b"}}},
+			expected: map[string][]string{"git-ref": {"main"}, "hook-commands": {"a, b"}},
+		},
+		{
+			name: "mappings from YAML",
+			values: map[string]any{
+				"mappings": []any{map[string]any{"root": "a", "target": "/b"}, "c:/d"},
+				"mapping":  []any{"e:/f"},
+			},
+			expected: map[string][]string{"mapping": {"a:/b", "c:/d", "e:/f"}},
+		},
+		{
+			name: "mappings from TOML arrays of tables",
+			values: map[string]any{
+				"mappings": []map[string]any{{"root": "a", "target": "/b", "sync-delete": true}},
+			},
+			expected: map[string][]string{"mapping": {"a:/b;sync-delete=true"}},
+		},
+		{
+			name: "layers",
+			values: map[string]any{
+				"layers": []map[string]any{{"adapter": "local", "local": map[string]any{"directory": "/a"}}},
+			},
+			expected: map[string][]string{"layer": {"local;local-directory=/a"}},
+		},
+		{
+			name:   "mappings should be a list",
+			values: map[string]any{"mappings": map[string]any{"root": "a"}},
+			err:    true,
+		},
+		{
+			name:   "nested list",
+			values: map[string]any{"include": []any{[]any{"a"}}},
+			err:    true,
+		},
+		{
+			name:   "unset variable",
+			values: map[string]any{"git": map[string]any{"ref": "${BEAMER_TEST_UNSET}"}},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := map[string][]string{}
+
+			err := flattenConfigFile("", test.values, options)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got: %v", options)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the structured list and the flag of the same option are merged in the undefined order of the table
+			for _, values := range options {
+				slices.Sort(values)
+			}
+
+			if !reflect.DeepEqual(options, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, options)
+			}
+		})
+	}
+}
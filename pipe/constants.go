@@ -3,5 +3,23 @@ package pipe
 type Adapter = string
 
 const (
-	ADAPTER_GIT Adapter = "git"
+	ADAPTER_GIT   Adapter = "git"
+	ADAPTER_HTTP  Adapter = "http"
+	ADAPTER_LOCAL Adapter = "local"
+	ADAPTER_OCI   Adapter = "oci"
+	ADAPTER_S3    Adapter = "s3"
+)
+
+type AtomicMode = string
+
+const (
+	ATOMIC_NONE AtomicMode = "none"
+	ATOMIC_FILE AtomicMode = "file"
+	ATOMIC_SWAP AtomicMode = "swap"
+)
+
+const (
+	ATOMIC_CURRENT_LINK        = "current"
+	ATOMIC_REVISIONS_DIRECTORY = ".beamer-revisions"
+	ATOMIC_STAGING_PREFIX      = ".staging-"
 )
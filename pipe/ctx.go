@@ -1,13 +1,24 @@
 package pipe
 
 import (
+	"context"
+
 	"gitlab.kilic.dev/docker/beamer/internal"
 	"gitlab.kilic.dev/docker/beamer/internal/comparator"
+	"gitlab.kilic.dev/docker/beamer/internal/hooks"
 	"gitlab.kilic.dev/docker/beamer/internal/operations"
 )
 
 type Ctx struct {
+	// Context is cancelled when the process is terminated while a cycle holds the lock.
+	Context        context.Context
 	FileComparator comparator.FileComparator
-	State          *internal.State
-	LockFile       *operations.LockFile
+	Mappings       []*internal.Mapping
+	// LockFiles are the locks of the target directories of the mappings in the order of acquiring them.
+	LockFiles  []*operations.LockFile
+	Changes    *internal.Changes
+	Hooks      []*hooks.Definition
+	Validators []*hooks.Definition
+	// Apply marks that the source has to be applied again, since the pinned revision has changed.
+	Apply bool
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/urfave/cli/v2"
 	"gitlab.kilic.dev/docker/beamer/internal/adapter"
 	"gitlab.kilic.dev/docker/beamer/internal/comparator"
+	"gitlab.kilic.dev/docker/beamer/internal/hooks"
 	. "gitlab.kilic.dev/libraries/plumber/v5"
 )
 
@@ -14,22 +15,39 @@ import (
 
 const (
 	CATEGORY_CONFIG = "Config"
+	CATEGORY_HOOKS  = "Hooks"
 )
 
 var Flags = CombineFlags(
 	[]cli.Flag{
 		// category config
 
+		&cli.StringFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "config",
+			Usage:    "Configuration file in YAML or TOML format with the options named after the flags, where the flags and the environment variables take precedence over the file.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_CONFIG"},
+		},
+
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "adapter",
-			Usage:       fmt.Sprintf("Mode to use. enum(%v)", []string{ADAPTER_GIT}),
+			Usage:       fmt.Sprintf("Mode to use. enum(%v)", []string{ADAPTER_GIT, ADAPTER_HTTP, ADAPTER_LOCAL, ADAPTER_OCI, ADAPTER_S3}),
 			Required:    false,
 			Value:       ADAPTER_GIT,
 			EnvVars:     []string{"BEAMER_ADAPTER"},
 			Destination: &TL.Pipe.Config.Adapter,
 		},
 
+		&cli.StringSliceFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "layer",
+			Usage:    fmt.Sprintf("Sources to combine in order instead of the single adapter in the format of adapter[;name=name][;option=value], where the later layers override the files of the earlier ones and the options are named after the flags of the adapter that they override. enum(%v)", []string{ADAPTER_GIT, ADAPTER_HTTP, ADAPTER_LOCAL, ADAPTER_OCI, ADAPTER_S3}),
+			Required: false,
+			EnvVars:  []string{"BEAMER_LAYER"},
+		},
+
 		&cli.DurationFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "interval",
@@ -50,6 +68,16 @@ var Flags = CombineFlags(
 			Destination: &TL.Pipe.Config.Once,
 		},
 
+		&cli.BoolFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "dry-run",
+			Usage:       "Print the planned changes once without touching the target directory or the state file.",
+			Required:    false,
+			Value:       false,
+			EnvVars:     []string{"BEAMER_DRY_RUN"},
+			Destination: &TL.Pipe.DryRun,
+		},
+
 		&cli.BoolFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "force-workflow",
@@ -83,13 +111,31 @@ var Flags = CombineFlags(
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "target-directory",
-			Usage:       "Target directory for the project.",
-			Required:    true,
+			Usage:       "Target directory for the project, which is required unless mappings are given.",
+			Required:    false,
 			Value:       "",
 			EnvVars:     []string{"BEAMER_TARGET_DIRECTORY"},
 			Destination: &TL.Pipe.TargetDirectory,
 		},
 
+		&cli.StringSliceFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "mapping",
+			Usage:    "Additional root directories of the source to sync into their own target directories in the format of root:target[;option=value], where the options name, state-file, ignore-file, include, exclude, template-files, template-values, owner, sync-delete and sync-delete-empty-directories override the global flags.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_MAPPING"},
+		},
+
+		&cli.StringFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "owner",
+			Usage:       "Owner of the synced files and directories in the format of user[:group] as names or ids, empty keeps the owner of the process.",
+			Required:    false,
+			Value:       "",
+			EnvVars:     []string{"BEAMER_OWNER"},
+			Destination: &TL.Pipe.Config.Owner,
+		},
+
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "ignore-file",
@@ -100,6 +146,22 @@ var Flags = CombineFlags(
 			Destination: &TL.Pipe.Config.IgnoreFile,
 		},
 
+		&cli.StringSliceFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "include",
+			Usage:    "Only sync the files that match any of the glob patterns relative to the root directory, where a pattern without a slash matches the name at any level, after the ignore file and the exclude patterns are applied.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_INCLUDE"},
+		},
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "exclude",
+			Usage:    "Skip the files and directories that match any of the glob patterns relative to the root directory, where a pattern without a slash matches the name at any level, which takes precedence over the ignore file.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_EXCLUDE"},
+		},
+
 		&cli.BoolFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "force-sync",
@@ -130,6 +192,26 @@ var Flags = CombineFlags(
 			Destination: &TL.Pipe.SyncDeleteEmptyDirectories,
 		},
 
+		&cli.StringFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "atomic",
+			Usage:       fmt.Sprintf("Update the target directory atomically, either by renaming every file into place or by swapping the symlinked %s directory to a new revision. enum(%v)", ATOMIC_CURRENT_LINK, []string{ATOMIC_NONE, ATOMIC_FILE, ATOMIC_SWAP}),
+			Required:    false,
+			Value:       ATOMIC_NONE,
+			EnvVars:     []string{"BEAMER_ATOMIC"},
+			Destination: &TL.Pipe.Config.Atomic,
+		},
+
+		&cli.IntFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "atomic-revisions",
+			Usage:       "Number of previous revisions to keep next to the current one when swapping.",
+			Required:    false,
+			Value:       2,
+			EnvVars:     []string{"BEAMER_ATOMIC_REVISIONS"},
+			Destination: &TL.Pipe.Config.AtomicRevisions,
+		},
+
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "state-file",
@@ -140,6 +222,36 @@ var Flags = CombineFlags(
 			Destination: &TL.Pipe.Config.StateFile,
 		},
 
+		&cli.IntFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "history",
+			Usage:       "Number of applied revisions to keep in the history of the state file for rolling back, 0 keeps all of them.",
+			Required:    false,
+			Value:       10,
+			EnvVars:     []string{"BEAMER_HISTORY"},
+			Destination: &TL.Pipe.Config.History,
+		},
+
+		&cli.StringFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "report-file",
+			Usage:       "File to write the JSON report of every sync cycle into, next to the state file, disabled when empty.",
+			Required:    false,
+			Value:       "",
+			EnvVars:     []string{"BEAMER_REPORT_FILE"},
+			Destination: &TL.Pipe.Config.ReportFile,
+		},
+
+		&cli.BoolFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "report-stdout",
+			Usage:       "Print the JSON report of every sync cycle to the standard output.",
+			Required:    false,
+			Value:       false,
+			EnvVars:     []string{"BEAMER_REPORT_STDOUT"},
+			Destination: &TL.Pipe.Config.ReportStdout,
+		},
+
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "lock-file",
@@ -150,6 +262,26 @@ var Flags = CombineFlags(
 			Destination: &TL.Pipe.Config.LockFile,
 		},
 
+		&cli.DurationFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "lock-timeout",
+			Usage:       "Duration to wait for the lock to be released by another process, 0 means waiting forever.",
+			Required:    false,
+			Value:       5 * time.Minute,
+			EnvVars:     []string{"BEAMER_LOCK_TIMEOUT"},
+			Destination: &TL.Pipe.Config.LockTimeout,
+		},
+
+		&cli.DurationFlag{
+			Category:    CATEGORY_CONFIG,
+			Name:        "lock-stale-timeout",
+			Usage:       "Age after which a lock that has not been refreshed by its holder is considered stale and will be broken, unless the holder is still running on the same host, 0 disables breaking the lock.",
+			Required:    false,
+			Value:       0,
+			EnvVars:     []string{"BEAMER_LOCK_STALE_TIMEOUT"},
+			Destination: &TL.Pipe.Config.LockStaleTimeout,
+		},
+
 		&cli.StringFlag{
 			Category:    CATEGORY_CONFIG,
 			Name:        "file-comparator",
@@ -168,13 +300,119 @@ var Flags = CombineFlags(
 			Value:    cli.NewStringSlice(".tmpl", ".gotmpl"),
 			EnvVars:  []string{"BEAMER_TEMPLATE_FILES"},
 		},
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_CONFIG,
+			Name:     "template-values",
+			Usage:    "Values files in YAML, JSON or TOML format that are merged in order and exposed to templates, relative to the root directory of the source or the local filesystem.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_TEMPLATE_VALUES"},
+		},
+	},
+	[]cli.Flag{
+		// category hooks
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_HOOKS,
+			Name:     "hook-commands",
+			Usage:    "Shell commands to run after files have changed, the changed files are passed with $BEAMER_CHANGED_FILES and the standard input.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_HOOK_COMMANDS"},
+		},
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_HOOKS,
+			Name:     "hook-signals",
+			Usage:    "Signals to send after files have changed in the format of signal:target, where the target is a pidfile as an absolute path or a process name.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_HOOK_SIGNALS"},
+		},
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_HOOKS,
+			Name:     "hook-http",
+			Usage:    "Endpoints to send the changed files as JSON after files have changed in the format of [method] url.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_HOOK_HTTP"},
+		},
+
+		&cli.StringSliceFlag{
+			Category: CATEGORY_HOOKS,
+			Name:     "hook-validate-commands",
+			Usage:    "Shell commands to validate the rendered files in the staging directory before anything is written to the target directory, the staging directory is passed with $BEAMER_DIRECTORY and a failure keeps the previous revision.",
+			Required: false,
+			EnvVars:  []string{"BEAMER_HOOK_VALIDATE_COMMANDS"},
+		},
+
+		&cli.DurationFlag{
+			Category:    CATEGORY_HOOKS,
+			Name:        "hook-timeout",
+			Usage:       "Timeout for every attempt of a hook.",
+			Required:    false,
+			Value:       hooks.DEFAULT_TIMEOUT,
+			EnvVars:     []string{"BEAMER_HOOK_TIMEOUT"},
+			Destination: &TL.Pipe.Config.HookTimeout,
+		},
+
+		&cli.IntFlag{
+			Category:    CATEGORY_HOOKS,
+			Name:        "hook-retries",
+			Usage:       "Number of times to retry a failed hook.",
+			Required:    false,
+			Value:       0,
+			EnvVars:     []string{"BEAMER_HOOK_RETRIES"},
+			Destination: &TL.Pipe.Config.HookRetries,
+		},
+
+		&cli.DurationFlag{
+			Category:    CATEGORY_HOOKS,
+			Name:        "hook-retry-delay",
+			Usage:       "Delay between the retries of a failed hook.",
+			Required:    false,
+			Value:       hooks.DEFAULT_RETRY_DELAY,
+			EnvVars:     []string{"BEAMER_HOOK_RETRY_DELAY"},
+			Destination: &TL.Pipe.Config.HookRetryDelay,
+		},
 	},
 	adapter.GitAdapterFlags,
+	adapter.HttpAdapterFlags,
+	adapter.LocalAdapterFlags,
+	adapter.OciAdapterFlags,
+	adapter.S3AdapterFlags,
 )
 
 //revive:disable:unused-parameter
+var SyncFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:        "once",
+		Usage:       "Run only a single sync cycle.",
+		Required:    false,
+		Value:       true,
+		Destination: &TL.Pipe.Config.Once,
+	},
+}
+
+var RollbackFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:        "revision",
+		Usage:       "Revision or a unique prefix of it from the history to roll back to, defaults to the revision before the current one.",
+		Required:    false,
+		EnvVars:     []string{"BEAMER_ROLLBACK_REVISION"},
+		Destination: &TL.Pipe.Config.RollbackRevision,
+	},
+}
+
 func ProcessFlags(tl *TaskList[Pipe]) error {
 	tl.Pipe.TemplateFiles = tl.CliContext.StringSlice("template-files")
+	tl.Pipe.Config.TemplateValues = tl.CliContext.StringSlice("template-values")
+	tl.Pipe.Config.Layers = tl.CliContext.StringSlice("layer")
+	tl.Pipe.Config.Mappings = tl.CliContext.StringSlice("mapping")
+	tl.Pipe.Config.Include = tl.CliContext.StringSlice("include")
+	tl.Pipe.Config.Exclude = tl.CliContext.StringSlice("exclude")
+	tl.Pipe.Config.HookCommands = tl.CliContext.StringSlice("hook-commands")
+	tl.Pipe.Config.HookSignals = tl.CliContext.StringSlice("hook-signals")
+	tl.Pipe.Config.HookHttp = tl.CliContext.StringSlice("hook-http")
+	tl.Pipe.Config.HookValidateCommands = tl.CliContext.StringSlice("hook-validate-commands")
 
 	return nil
 }
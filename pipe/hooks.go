@@ -0,0 +1,114 @@
+package pipe
+
+import (
+	"slices"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/hooks"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+func Hooks(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("hooks").
+		ShouldDisable(func(t *Task[Pipe]) bool {
+			return len(t.Pipe.Ctx.Hooks) == 0 || t.Pipe.DryRun
+		}).
+		Set(func(t *Task[Pipe]) error {
+			changes := t.Pipe.Ctx.Changes
+
+			files := []string{}
+			for _, action := range []internal.ChangeAction{
+				internal.CHANGE_ACTION_CREATE,
+				internal.CHANGE_ACTION_UPDATE,
+				internal.CHANGE_ACTION_CHMOD,
+				internal.CHANGE_ACTION_DELETE,
+			} {
+				files = append(files, changes.Paths(action)...)
+			}
+			slices.Sort(files)
+			files = slices.Compact(files)
+
+			if len(files) == 0 {
+				t.Log.Debugf("No files have changed, skipping hooks.")
+
+				return nil
+			}
+
+			// the hooks run once for all the mappings, so they start in the target of the first one
+			event := &hooks.Event{
+				Directory: currentDirectory(t, t.Pipe.Ctx.Mappings[0]),
+				Files:     files,
+			}
+			if revision, err := a.Revision(); err == nil {
+				event.Revision = revision
+			}
+
+			// a failing hook does not fail the cycle, since the files are already applied
+			for _, hook := range t.Pipe.Ctx.Hooks {
+				t.Log.Infof("Running hook: %s", hook)
+
+				if err := hook.Run(t.Pipe.Ctx.Context, t.Log, event); err != nil {
+					t.Log.Errorf("%v", err)
+					changes.AddError(err)
+
+					continue
+				}
+
+				t.Log.Debugf("Hook finished: %s", hook)
+			}
+
+			return nil
+		})
+}
+
+func setupHooks(t *Task[Pipe]) error {
+	configured := []hooks.Hook{}
+
+	for _, command := range t.Pipe.Config.HookCommands {
+		configured = append(configured, hooks.NewCommandHook(command))
+	}
+
+	for _, value := range t.Pipe.Config.HookSignals {
+		hook, err := hooks.ParseSignalHook(value)
+		if err != nil {
+			return err
+		}
+
+		configured = append(configured, hook)
+	}
+
+	for _, value := range t.Pipe.Config.HookHttp {
+		hook, err := hooks.ParseHttpHook(value)
+		if err != nil {
+			return err
+		}
+
+		configured = append(configured, hook)
+	}
+
+	for _, command := range t.Pipe.Config.HookValidateCommands {
+		t.Pipe.Ctx.Validators = append(t.Pipe.Ctx.Validators, &hooks.Definition{
+			Hook:    hooks.NewCommandHook(command),
+			Timeout: t.Pipe.Config.HookTimeout,
+		})
+	}
+
+	for _, hook := range configured {
+		t.Pipe.Ctx.Hooks = append(t.Pipe.Ctx.Hooks, &hooks.Definition{
+			Hook:       hook,
+			Timeout:    t.Pipe.Config.HookTimeout,
+			Retries:    t.Pipe.Config.HookRetries,
+			RetryDelay: t.Pipe.Config.HookRetryDelay,
+		})
+	}
+
+	if len(t.Pipe.Ctx.Hooks) > 0 {
+		t.Log.Infof("Using %d hooks.", len(t.Pipe.Ctx.Hooks))
+	}
+
+	if len(t.Pipe.Ctx.Validators) > 0 {
+		t.Log.Infof("Using %d validation hooks.", len(t.Pipe.Ctx.Validators))
+	}
+
+	return nil
+}
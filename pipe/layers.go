@@ -0,0 +1,67 @@
+package pipe
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal/adapter"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// parseLayers parses the layers in the format of adapter[;option=value]..., where the options are named after the flags of the adapter.
+func parseLayers(t *Task[Pipe]) ([]adapter.LayerDefinition, error) {
+	layers := []adapter.LayerDefinition{}
+	names := []string{}
+
+	for i, value := range t.Pipe.Config.Layers {
+		parts := strings.Split(value, ";")
+
+		layer := adapter.LayerDefinition{
+			Adapter: strings.TrimSpace(parts[0]),
+			Options: map[string][]string{},
+		}
+
+		if !slices.Contains([]string{ADAPTER_GIT, ADAPTER_HTTP, ADAPTER_LOCAL, ADAPTER_OCI, ADAPTER_S3}, layer.Adapter) {
+			return nil, fmt.Errorf("Layer adapter is not supported, expected adapter[;option=value]: %s", value)
+		}
+
+		for _, part := range parts[1:] {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+
+			key, option, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("Layer option is not valid, expected option=value: %s -> %s", value, part)
+			}
+			key = strings.TrimSpace(key)
+			option = strings.TrimSpace(option)
+
+			if key == "name" {
+				layer.Name = option
+
+				continue
+			}
+
+			layer.Options[key] = append(layer.Options[key], option)
+		}
+
+		// the name is used for the working directory and the state of the layer, so it should be stable
+		if layer.Name == "" {
+			layer.Name = fmt.Sprintf("%d-%s", i, layer.Adapter)
+		}
+
+		if filepath.Base(layer.Name) != layer.Name || strings.HasPrefix(layer.Name, ".") {
+			return nil, fmt.Errorf("Layer name can only be a plain directory name: %s", layer.Name)
+		} else if slices.Contains(names, layer.Name) {
+			return nil, fmt.Errorf("Layer name is not unique: %s", layer.Name)
+		}
+		names = append(names, layer.Name)
+
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
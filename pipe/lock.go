@@ -0,0 +1,123 @@
+package pipe
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+func Lock(tl *TaskList[Pipe], jobs ...Job) *Task[Pipe] {
+	return tl.CreateTask("lock").
+		Set(func(t *Task[Pipe]) error {
+			// dry runs do not touch the target directory, so they do not need to hold the lock
+			if t.Pipe.DryRun {
+				return tl.RunJobs(tl.JobSequence(jobs...))
+			}
+
+			locks := []*operations.LockFile{}
+			// the locks are released in the reverse order of acquiring them
+			release := func() {
+				for _, lock := range slices.Backward(locks) {
+					if err := lock.Unlock(); err != nil {
+						t.Log.Errorf("Can not release the lock: %s -> %v", lock.Path(), err)
+
+						continue
+					}
+
+					t.Log.Debugf("Lock released: %s", lock.Path())
+				}
+			}
+
+			for _, lock := range t.Pipe.Ctx.LockFiles {
+				if err := acquire(t, lock); err != nil {
+					release()
+
+					return err
+				}
+
+				locks = append(locks, lock)
+			}
+
+			// termination cancels the cycle instead of stopping the process, so that the lock is only released after the jobs have returned
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Pipe.Ctx.Context = ctx
+
+			received := make(chan os.Signal, 1)
+			done := make(chan struct{})
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				select {
+				case sig := <-signals:
+					t.Log.Warnf("Received %s, cancelling the cycle before releasing the lock.", sig)
+
+					received <- sig
+					cancel()
+				case <-done:
+				}
+			}()
+
+			err := tl.RunJobs(tl.JobSequence(jobs...))
+
+			close(done)
+			wg.Wait()
+			signal.Stop(signals)
+			cancel()
+			t.Pipe.Ctx.Context = context.Background()
+
+			release()
+
+			// the default handling of the signal terminates the process, now that the lock is released
+			select {
+			case sig := <-received:
+				//nolint:errcheck
+				syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+			default:
+			}
+
+			return err
+		})
+}
+
+// acquire takes the lock, waiting for the other holder to release it.
+func acquire(t *Task[Pipe], lock *operations.LockFile) error {
+	if !lock.IsLocked() {
+		if holder, err := lock.Holder(); err != nil {
+			return err
+		} else if holder != nil {
+			t.Log.Warnf("Recovering stale lock that was not released: %s by %s", lock.Path(), holder)
+		}
+	}
+
+	ok, err := lock.TryLock()
+	if err != nil {
+		return err
+	} else if !ok {
+		holder, err := lock.Holder()
+		if err != nil {
+			return err
+		}
+
+		t.Log.Infof("Waiting for the lock: %s held by %s", lock.Path(), holder)
+
+		if err := lock.Lock(); err != nil {
+			return err
+		}
+	}
+
+	t.Log.Debugf("Lock acquired: %s", lock.Path())
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// setupMappings creates the mappings from the target directory and the mapping flags, where the first mapping holds the lock and the report.
+func setupMappings(t *Task[Pipe]) ([]*internal.Mapping, error) {
+	mappings := []*internal.Mapping{}
+
+	if t.Pipe.TargetDirectory != "" {
+		m, err := defaultMapping(t)
+		if err != nil {
+			return nil, err
+		}
+
+		m.RootDirectory = t.Pipe.RootDirectory
+		m.TargetDirectory = t.Pipe.TargetDirectory
+		m.Name = m.TargetDirectory
+
+		mappings = append(mappings, m)
+	}
+
+	for _, value := range t.Pipe.Config.Mappings {
+		m, err := parseMapping(t, value)
+		if err != nil {
+			return nil, err
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	if len(mappings) == 0 {
+		return nil, errors.New("Target directory or at least one mapping is required")
+	}
+
+	names := []string{}
+	states := []string{}
+	for _, m := range mappings {
+		// the root directories are relative to the source, where the files are resolved against its root
+		m.RootDirectory = filepath.Join("/", m.RootDirectory)
+
+		if slices.Contains(names, m.Name) {
+			return nil, fmt.Errorf("Mapping name is not unique, set a name for the mapping: %s", m.Name)
+		}
+		names = append(names, m.Name)
+
+		state := filepath.Join(m.TargetDirectory, m.StateFile)
+		if slices.Contains(states, state) {
+			return nil, fmt.Errorf("State file is shared between mappings, set a state file for the mapping: %s -> %s", m.Name, state)
+		}
+		states = append(states, state)
+	}
+
+	return mappings, nil
+}
+
+// defaultMapping creates a mapping with the settings of the global flags, which the mapping flags override.
+func defaultMapping(t *Task[Pipe]) (*internal.Mapping, error) {
+	m := &internal.Mapping{
+		RootDirectory:              "/",
+		StateFile:                  t.Pipe.Config.StateFile,
+		IgnoreFile:                 t.Pipe.Config.IgnoreFile,
+		Include:                    t.Pipe.Config.Include,
+		Exclude:                    t.Pipe.Config.Exclude,
+		TemplateFiles:              t.Pipe.TemplateFiles,
+		TemplateValues:             t.Pipe.Config.TemplateValues,
+		SyncDelete:                 t.Pipe.SyncDelete,
+		SyncDeleteEmptyDirectories: t.Pipe.SyncDeleteEmptyDirectories,
+	}
+
+	if t.Pipe.Config.Owner != "" {
+		owner, err := operations.ParseOwner(t.Pipe.Config.Owner)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Owner = owner
+	}
+
+	return m, nil
+}
+
+// parseMapping parses a mapping in the format of root:target[;option=value]..., where the list options can be repeated.
+func parseMapping(t *Task[Pipe], value string) (*internal.Mapping, error) {
+	m, err := defaultMapping(t)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(value, ";")
+
+	root, target, ok := strings.Cut(parts[0], ":")
+	if !ok || strings.TrimSpace(target) == "" {
+		return nil, fmt.Errorf("Mapping is not valid, expected root:target[;option=value]: %s", value)
+	}
+
+	if root = strings.TrimSpace(root); root != "" {
+		m.RootDirectory = root
+	}
+	m.TargetDirectory = strings.TrimSpace(target)
+	m.Name = m.TargetDirectory
+
+	// the lists of the global flags are replaced as a whole by the options of the mapping
+	lists := map[string]*[]string{
+		"include":         &m.Include,
+		"exclude":         &m.Exclude,
+		"template-files":  &m.TemplateFiles,
+		"template-values": &m.TemplateValues,
+	}
+	replaced := []string{}
+
+	for _, part := range parts[1:] {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		key, option, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("Mapping option is not valid, expected option=value: %s -> %s", value, part)
+		}
+		key = strings.TrimSpace(key)
+		option = strings.TrimSpace(option)
+
+		if list, ok := lists[key]; ok {
+			if !slices.Contains(replaced, key) {
+				*list = []string{}
+				replaced = append(replaced, key)
+			}
+
+			if option != "" {
+				*list = append(*list, option)
+			}
+
+			continue
+		}
+
+		switch key {
+		case "name":
+			m.Name = option
+		case "state-file":
+			m.StateFile = option
+		case "ignore-file":
+			m.IgnoreFile = option
+		case "owner":
+			if option == "" {
+				m.Owner = nil
+
+				continue
+			}
+
+			owner, err := operations.ParseOwner(option)
+			if err != nil {
+				return nil, err
+			}
+			m.Owner = owner
+		case "sync-delete", "sync-delete-empty-directories":
+			enabled, err := strconv.ParseBool(option)
+			if err != nil {
+				return nil, fmt.Errorf("Mapping option is not a boolean: %s -> %s", value, part)
+			}
+
+			if key == "sync-delete" {
+				m.SyncDelete = enabled
+			} else {
+				m.SyncDeleteEmptyDirectories = enabled
+			}
+		default:
+			return nil, fmt.Errorf("Mapping option is not supported: %s -> %s", value, key)
+		}
+	}
+
+	if m.Name == "" {
+		return nil, fmt.Errorf("Mapping name can not be empty: %s", value)
+	} else if m.StateFile == "" {
+		return nil, fmt.Errorf("Mapping state file can not be empty: %s", value)
+	}
+
+	return m, nil
+}
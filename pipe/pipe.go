@@ -19,14 +19,36 @@ type (
 	}
 
 	Config struct {
-		Adapter        Adapter `validate:"required,oneof=git"`
-		Once           bool
-		StateFile      string
-		LockFile       string
-		Interval       time.Duration
-		IgnoreFile     string
-		ForceWorkflow  bool
-		FileComparator comparator.Comparator `validate:"oneof=sha256 md5"`
+		Adapter              Adapter `validate:"required,oneof=git http local oci s3"`
+		Layers               []string
+		Once                 bool
+		StateFile            string
+		History              int `validate:"gte=0"`
+		RollbackRevision     string
+		RenderFile           string
+		LockFile             string
+		LockTimeout          time.Duration
+		LockStaleTimeout     time.Duration
+		Interval             time.Duration
+		Mappings             []string
+		Owner                string
+		IgnoreFile           string
+		Include              []string
+		Exclude              []string
+		ForceWorkflow        bool
+		Atomic               AtomicMode            `validate:"oneof=none file swap"`
+		AtomicRevisions      int                   `validate:"gte=0"`
+		FileComparator       comparator.Comparator `validate:"oneof=sha256 md5"`
+		TemplateValues       []string
+		ReportStdout         bool
+		ReportFile           string
+		HookCommands         []string
+		HookSignals          []string
+		HookHttp             []string
+		HookValidateCommands []string
+		HookTimeout          time.Duration
+		HookRetries          int `validate:"gte=0"`
+		HookRetryDelay       time.Duration
 	}
 )
 
@@ -38,25 +60,28 @@ var a adapter.Adapter
 func New(p *Plumber) *TaskList[Pipe] {
 	return TL.New(p).
 		SetRuntimeDepth(2).
-		ShouldRunBefore(func(tl *TaskList[Pipe]) error {
-			if err := ProcessFlags(tl); err != nil {
-				return err
-			}
-
-			return tl.RunJobs(Setup(tl).Job())
-		}).
+		ShouldRunBefore(setup).
 		Set(func(tl *TaskList[Pipe]) Job {
-			jobs := tl.JobSequence(
-				a.Sync(),
-				Workflow(tl).Job(),
-				a.Finalize(),
-			)
+			jobs := Cycle(
+				tl,
+				Lock(
+					tl,
+					Pin(
+						tl,
+						a.Sync(),
+						Workflow(tl).Job(),
+						a.Finalize(),
+						Hooks(tl).Job(),
+						Plan(tl).Job(),
+					).Job(),
+				).Job(),
+			).Job()
 
 			return tl.JobSequence(
 				a.Init(),
 				tl.JobIf(
 					func(_ floc.Context) bool {
-						return tl.Pipe.Config.Once
+						return tl.Pipe.Config.Once || tl.Pipe.DryRun
 					},
 					tl.JobThen(jobs),
 					tl.JobElse(
@@ -69,3 +94,72 @@ func New(p *Plumber) *TaskList[Pipe] {
 			)
 		})
 }
+
+// NewRollback restores the target directory to a previous revision from the history and pins it.
+func NewRollback(p *Plumber) *TaskList[Pipe] {
+	return TL.New(p).
+		SetRuntimeDepth(2).
+		ShouldRunBefore(setup).
+		Set(func(tl *TaskList[Pipe]) Job {
+			return Cycle(
+				tl,
+				Lock(
+					tl,
+					Rollback(tl).Job(),
+					apply(tl),
+					Hooks(tl).Job(),
+				).Job(),
+			).Job()
+		})
+}
+
+// NewUnpin releases the pinned revision and applies the latest revision of the source again.
+func NewUnpin(p *Plumber) *TaskList[Pipe] {
+	return TL.New(p).
+		SetRuntimeDepth(2).
+		ShouldRunBefore(setup).
+		Set(func(tl *TaskList[Pipe]) Job {
+			return Cycle(
+				tl,
+				Lock(
+					tl,
+					Unpin(tl).Job(),
+					apply(tl),
+					Hooks(tl).Job(),
+				).Job(),
+			).Job()
+		})
+}
+
+func setup(tl *TaskList[Pipe]) error {
+	// the commands that never touch the target directory force the dry run, which the config file can not turn off
+	forced := tl.Pipe.DryRun
+
+	if err := loadConfigFile(tl); err != nil {
+		return err
+	}
+
+	tl.Pipe.DryRun = tl.Pipe.DryRun || forced
+
+	if err := ProcessFlags(tl); err != nil {
+		return err
+	}
+
+	return tl.RunJobs(Setup(tl).Job())
+}
+
+// apply brings the source to the pinned revision and applies it, when the pin has been changed.
+func apply(tl *TaskList[Pipe]) Job {
+	return tl.JobIf(
+		func(_ floc.Context) bool {
+			return tl.Pipe.Ctx.Apply
+		},
+		tl.JobThen(
+			tl.JobSequence(
+				a.Init(),
+				Workflow(tl).Job(),
+				a.Finalize(),
+			),
+		),
+	)
+}
@@ -0,0 +1,51 @@
+package pipe
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+func Plan(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("plan").
+		ShouldDisable(func(t *Task[Pipe]) bool {
+			return !t.Pipe.DryRun
+		}).
+		Set(func(t *Task[Pipe]) error {
+			changes := t.Pipe.Ctx.Changes
+
+			out := &strings.Builder{}
+
+			fmt.Fprintf(
+				out,
+				"Plan: %d to create, %d to update, %d to chmod, %d to delete, %d directories to create.\n",
+				changes.Count(internal.CHANGE_ACTION_CREATE),
+				changes.Count(internal.CHANGE_ACTION_UPDATE),
+				changes.Count(internal.CHANGE_ACTION_CHMOD),
+				changes.Count(internal.CHANGE_ACTION_DELETE),
+				changes.Count(internal.CHANGE_ACTION_MKDIR),
+			)
+
+			for _, change := range changes.List() {
+				switch change.Action {
+				case internal.CHANGE_ACTION_CREATE, internal.CHANGE_ACTION_CHMOD, internal.CHANGE_ACTION_MKDIR:
+					fmt.Fprintf(out, "%-6s %s (%s)\n", change.Action, change.Path, change.Mode)
+				case internal.CHANGE_ACTION_UPDATE, internal.CHANGE_ACTION_DELETE:
+					fmt.Fprintf(out, "%-6s %s\n", change.Action, change.Path)
+				default:
+					continue
+				}
+
+				if change.Diff != "" {
+					fmt.Fprint(out, change.Diff)
+				}
+			}
+
+			_, err := fmt.Fprint(os.Stdout, out.String())
+
+			return err
+		})
+}
@@ -0,0 +1,68 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// Render prints a single template to the standard output, where the file is relative to the root directory of the first mapping that contains it or the local filesystem.
+func Render(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("render").
+		Set(func(t *Task[Pipe]) error {
+			if t.Pipe.Config.RenderFile == "" {
+				return errors.New("File to render is required as an argument")
+			}
+
+			m := t.Pipe.Ctx.Mappings[0]
+			f := operations.NewFile(t.Pipe.Config.RenderFile)
+
+			found := false
+			for _, mapping := range t.Pipe.Ctx.Mappings {
+				if sf := operations.NewFile(t.Pipe.WorkingDirectory, mapping.RootDirectory, t.Pipe.Config.RenderFile); sf.IsFile() {
+					m, f, found = mapping, sf, true
+
+					break
+				}
+			}
+
+			if !found {
+				t.Log.Debugf("File does not exist in the source, falling back to the local filesystem: %s", f.Abs())
+			}
+
+			if !f.IsFile() {
+				return fmt.Errorf("File to render does not exist: %s", t.Pipe.Config.RenderFile)
+			}
+
+			content, err := f.ReadFile()
+			if err != nil {
+				return err
+			}
+
+			data, err := NewTemplateData(t, m)
+			if err != nil {
+				return err
+			}
+
+			rel := filepath.Clean(t.Pipe.Config.RenderFile)
+			target := rel
+			if slices.Contains(m.TemplateFiles, f.Ext()) {
+				target = strings.TrimSuffix(rel, f.Ext())
+			}
+
+			template, err := InlineTemplate(string(content), data.ForFile(rel, target))
+			if err != nil {
+				return fmt.Errorf("Can not inline template for file: %s -> %w", f.Abs(), err)
+			}
+
+			_, err = fmt.Fprint(os.Stdout, template)
+
+			return err
+		})
+}
@@ -0,0 +1,131 @@
+package pipe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/adapter"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// Report is the machine readable summary of a sync cycle.
+type Report struct {
+	Revision    *adapter.Revision `json:"revision,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	Duration    string            `json:"duration"`
+	DryRun      bool              `json:"dryRun"`
+	Created     []string          `json:"created"`
+	Updated     []string          `json:"updated"`
+	Unchanged   []string          `json:"unchanged"`
+	Deleted     []string          `json:"deleted"`
+	Chmod       []string          `json:"chmod"`
+	Directories []string          `json:"directories"`
+	Templated   []string          `json:"templated"`
+	Ignored     []string          `json:"ignored"`
+	Conflicts   []string          `json:"conflicts"`
+	Errors      []string          `json:"errors"`
+}
+
+// Cycle runs the jobs as a sync cycle and emits the report of the recorded changes afterwards, even if the cycle fails.
+func Cycle(tl *TaskList[Pipe], jobs ...Job) *Task[Pipe] {
+	return tl.CreateTask("cycle").
+		Set(func(t *Task[Pipe]) error {
+			changes := t.Pipe.Ctx.Changes
+			changes.Reset()
+
+			started := time.Now()
+			err := tl.RunJobs(tl.JobSequence(jobs...))
+
+			if !t.Pipe.Config.ReportStdout && t.Pipe.Config.ReportFile == "" {
+				return err
+			}
+
+			report := &Report{
+				StartedAt:   started,
+				Duration:    time.Since(started).String(),
+				DryRun:      t.Pipe.DryRun,
+				Created:     changes.Paths(internal.CHANGE_ACTION_CREATE),
+				Updated:     changes.Paths(internal.CHANGE_ACTION_UPDATE),
+				Unchanged:   changes.Paths(internal.CHANGE_ACTION_UNCHANGED),
+				Deleted:     changes.Paths(internal.CHANGE_ACTION_DELETE),
+				Chmod:       changes.Paths(internal.CHANGE_ACTION_CHMOD),
+				Directories: changes.Paths(internal.CHANGE_ACTION_MKDIR),
+				Templated:   changes.Paths(internal.CHANGE_ACTION_TEMPLATED),
+				Ignored:     changes.Paths(internal.CHANGE_ACTION_IGNORED),
+				Conflicts:   changes.Paths(internal.CHANGE_ACTION_CONFLICT),
+				Errors:      []string{},
+			}
+
+			if revision, e := a.Revision(); e == nil {
+				report.Revision = revision
+			} else {
+				t.Log.Debugf("Can not determine the revision for the report: %v", e)
+			}
+
+			for _, e := range changes.Errors() {
+				report.Errors = append(report.Errors, e.Error())
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+
+			if e := writeReport(t, report); e != nil {
+				t.Log.Errorf("Can not write the report -> %v", e)
+			}
+
+			return err
+		})
+}
+
+func writeReport(t *Task[Pipe], report *Report) error {
+	f, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	if t.Pipe.Config.ReportStdout {
+		if _, err := fmt.Fprintln(os.Stdout, string(f)); err != nil {
+			return err
+		}
+	}
+
+	if t.Pipe.Config.ReportFile == "" {
+		return nil
+	} else if t.Pipe.DryRun {
+		t.Log.Debugf("Skipping writing report file in dry run: %s", t.Pipe.Config.ReportFile)
+
+		return nil
+	}
+
+	// the report of all the mappings is written next to the lock file of the first one
+	path := filepath.Join(t.Pipe.Ctx.Mappings[0].TargetDirectory, t.Pipe.Config.ReportFile)
+
+	t.Log.Debugf("Writing report file: %s", path)
+
+	// the report is replaced atomically, since it is consumed by the other processes in the container
+	temp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s-", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(f); err != nil {
+		temp.Close()
+
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
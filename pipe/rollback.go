@@ -0,0 +1,263 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// Pin refreshes the pinned revisions from the state files and runs the jobs, unless all the targets are pinned to local snapshots.
+func Pin(tl *TaskList[Pipe], jobs ...Job) *Task[Pipe] {
+	return tl.CreateTask("pin").
+		Set(func(t *Task[Pipe]) error {
+			snapshots := 0
+
+			for _, m := range t.Pipe.Ctx.Mappings {
+				changed, err := m.State.Refresh()
+				if err != nil {
+					return err
+				}
+
+				pin := m.State.Pinned()
+
+				if changed {
+					if pin == nil {
+						t.Log.Infof("Target of mapping %s is unpinned, applying the latest revision.", m)
+					} else {
+						t.Log.Warnf("Target of mapping %s is pinned to revision: %s", m, pin)
+					}
+
+					m.State.SetDirty()
+				}
+
+				if pin != nil && pin.Snapshot != "" {
+					snapshots++
+				}
+			}
+
+			if snapshots == len(t.Pipe.Ctx.Mappings) {
+				t.Log.Infof("Targets are pinned to snapshots, skipping the sync.")
+
+				return nil
+			}
+
+			return tl.RunJobs(tl.JobSequence(jobs...))
+		})
+}
+
+func Rollback(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("rollback").
+		Set(func(t *Task[Pipe]) error {
+			// the mappings are applied together, so the revision is looked up in the history of the first one
+			first := t.Pipe.Ctx.Mappings[0]
+
+			history, err := first.State.History()
+			if err != nil {
+				return err
+			}
+
+			entry, err := findRollbackRevision(history, first.State.Pinned(), t.Pipe.Config.RollbackRevision)
+			if err != nil {
+				return err
+			}
+
+			t.Log.Infof("Rolling back to revision: %s applied at %s", entry.Revision, entry.AppliedAt.Format(time.RFC3339))
+
+			// every mapping has to be restorable before any of the targets is touched
+			snapshots := map[*internal.Mapping]string{}
+			for _, m := range t.Pipe.Ctx.Mappings {
+				history, err := m.State.History()
+				if err != nil {
+					return err
+				}
+
+				for _, e := range history {
+					if e.Revision == entry.Revision {
+						snapshots[m] = e.Snapshot
+					}
+				}
+
+				if snapshotDirectory(t, m, snapshots[m]) != "" {
+					continue
+				} else if len(t.Pipe.Config.Layers) > 0 {
+					return fmt.Errorf("Local snapshot of the revision is not available for mapping %s and the layers can not check out previous revisions: %s", m, entry.Revision)
+				} else if t.Pipe.Config.Adapter != ADAPTER_GIT {
+					return fmt.Errorf(
+						"Local snapshot of the revision is not available for mapping %s and the %s adapter can not check out previous revisions: %s",
+						m,
+						t.Pipe.Config.Adapter,
+						entry.Revision,
+					)
+				}
+			}
+
+			for _, m := range t.Pipe.Ctx.Mappings {
+				pin := &internal.StatePin{
+					Revision: entry.Revision,
+					PinnedAt: time.Now(),
+				}
+
+				if snapshot := snapshotDirectory(t, m, snapshots[m]); snapshot != "" {
+					t.Log.Infof("Restoring the local snapshot: %s", snapshot)
+
+					if err := recordSnapshotChanges(t, m, snapshot); err != nil {
+						return err
+					}
+
+					if !t.Pipe.DryRun {
+						if err := pointCurrentTo(t, m, snapshots[m]); err != nil {
+							return err
+						}
+					}
+
+					pin.Snapshot = snapshots[m]
+					m.State.SetPin(pin)
+
+					if err := m.State.Flush(); err != nil {
+						return err
+					}
+
+					continue
+				}
+
+				t.Log.Infof("Checking out the revision from the source for mapping %s: %s", m, entry.Revision)
+
+				// the pin is committed together with the state of the adapter after the revision is applied
+				m.State.SetPin(pin)
+				m.State.SetDirty()
+				t.Pipe.Ctx.Apply = true
+				t.Pipe.Config.ForceWorkflow = true
+			}
+
+			return nil
+		})
+}
+
+func Unpin(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("unpin").
+		Set(func(t *Task[Pipe]) error {
+			for _, m := range t.Pipe.Ctx.Mappings {
+				pin := m.State.Pinned()
+				if pin == nil {
+					continue
+				}
+
+				t.Log.Infof("Unpinning the target of mapping %s from revision: %s", m, pin)
+
+				// the latest revision is applied again, since the target might be a snapshot that the adapter does not know about
+				m.State.SetPin(nil)
+				m.State.SetDirty()
+				t.Pipe.Ctx.Apply = true
+				t.Pipe.Config.ForceWorkflow = true
+			}
+
+			if !t.Pipe.Ctx.Apply {
+				t.Log.Infof("Target is not pinned, nothing to do.")
+			}
+
+			return nil
+		})
+}
+
+// recordHistory adds the applied revision to the history in the state file of the mapping, the revisions that are applied while pinned are not recorded.
+func recordHistory(t *Task[Pipe], m *internal.Mapping, manifest operations.Manifest, snapshot string) error {
+	if t.Pipe.DryRun || m.State.Pinned() != nil {
+		return nil
+	}
+
+	revision, err := a.Revision()
+	if err != nil {
+		t.Log.Warnf("Can not determine the revision for the history: %v", err)
+
+		return nil
+	}
+
+	m.State.AddHistory(internal.StateHistory{
+		Revision:  revision.Revision,
+		Adapter:   revision.Adapter,
+		AppliedAt: time.Now(),
+		Snapshot:  snapshot,
+		Files:     manifest,
+	})
+
+	return nil
+}
+
+// findRollbackRevision finds the revision in the history with the given prefix, or the one that was applied before the current one.
+func findRollbackRevision(history []internal.StateHistory, pin *internal.StatePin, revision string) (*internal.StateHistory, error) {
+	if len(history) == 0 {
+		return nil, errors.New("No revisions are recorded in the history of the state file")
+	}
+
+	if revision != "" {
+		for i := len(history) - 1; i >= 0; i-- {
+			if strings.HasPrefix(history[i].Revision, revision) {
+				return &history[i], nil
+			}
+		}
+
+		return nil, fmt.Errorf("Revision is not recorded in the history of the state file: %s", revision)
+	}
+
+	current := len(history) - 1
+	if pin != nil {
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Revision == pin.Revision {
+				current = i
+
+				break
+			}
+		}
+	}
+
+	if current == 0 {
+		return nil, fmt.Errorf("No revision is recorded before the current one: %s", history[current].Revision)
+	}
+
+	return &history[current-1], nil
+}
+
+// recordSnapshotChanges records the changes between the current revision and the snapshot that is restored.
+func recordSnapshotChanges(t *Task[Pipe], m *internal.Mapping, snapshot string) error {
+	current := currentDirectory(t, m)
+
+	previous := operations.Manifest{}
+	if _, err := os.Stat(current); err == nil {
+		if previous, err = operations.NewManifest(current + string(filepath.Separator)); err != nil {
+			return err
+		}
+	}
+
+	next, err := operations.NewManifest(snapshot)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range previous.Changed(next) {
+		action := internal.CHANGE_ACTION_UPDATE
+		if _, ok := previous[path]; !ok {
+			action = internal.CHANGE_ACTION_CREATE
+		}
+
+		t.Pipe.Ctx.Changes.Add(internal.Change{
+			Action: action,
+			Path:   filepath.Join(current, path),
+		})
+	}
+
+	for _, path := range previous.Removed(next) {
+		t.Pipe.Ctx.Changes.Add(internal.Change{
+			Action: internal.CHANGE_ACTION_DELETE,
+			Path:   filepath.Join(current, path),
+		})
+	}
+
+	return nil
+}
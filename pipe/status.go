@@ -0,0 +1,140 @@
+package pipe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// Status prints the state of the target directories without touching the source or the targets.
+func Status(tl *TaskList[Pipe]) *Task[Pipe] {
+	return tl.CreateTask("status").
+		Set(func(t *Task[Pipe]) error {
+			out := &strings.Builder{}
+
+			for _, lock := range t.Pipe.Ctx.LockFiles {
+				if lock.IsLocked() {
+					holder, err := lock.Holder()
+					if err != nil {
+						return err
+					}
+
+					fmt.Fprintf(out, "Lock: %s held by %s\n", lock.Path(), holder)
+				} else {
+					fmt.Fprintf(out, "Lock: %s free\n", lock.Path())
+				}
+			}
+
+			for _, m := range t.Pipe.Ctx.Mappings {
+				if err := mappingStatus(t, m, out); err != nil {
+					return err
+				}
+			}
+
+			_, err := fmt.Fprint(os.Stdout, out.String())
+
+			return err
+		})
+}
+
+// mappingStatus prints the state of the target directory of a single mapping.
+func mappingStatus(t *Task[Pipe], m *internal.Mapping, out *strings.Builder) error {
+	fmt.Fprintf(out, "\nMapping: %s\n", m)
+	fmt.Fprintf(out, "Root directory: %s\n", m.RootDirectory)
+	fmt.Fprintf(out, "Target directory: %s\n", currentDirectory(t, m))
+	fmt.Fprintf(out, "State file: %s\n", filepath.Join(m.TargetDirectory, m.StateFile))
+
+	history, err := m.State.History()
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		fmt.Fprintln(out, "Last applied revision: none")
+	} else {
+		last := history[len(history)-1]
+		fmt.Fprintf(out, "Last applied revision: %s (%s) at %s\n", last.Revision, last.Adapter, last.AppliedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "History: %d revisions\n", len(history))
+
+	if pin := m.State.Pinned(); pin != nil {
+		fmt.Fprintf(out, "Pinned: %s since %s\n", pin, pin.PinnedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(out, "Pinned: no")
+	}
+
+	modified, err := modifiedFiles(t, m)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case modified == nil:
+		fmt.Fprintln(out, "Target: unknown")
+	case len(modified) == 0:
+		fmt.Fprintln(out, "Target: clean")
+	default:
+		fmt.Fprintf(out, "Target: dirty, %d files differ from the last applied revision\n", len(modified))
+		for _, path := range modified {
+			fmt.Fprintf(out, "  %s\n", path)
+		}
+	}
+
+	state, err := m.State.Read()
+	if err != nil {
+		return err
+	} else if state != nil {
+		fmt.Fprintf(out, "Adapter state: %s\n", state)
+	}
+
+	return nil
+}
+
+// modifiedFiles compares the target directory with the manifest of the applied revision, it is nil when there is no manifest to compare to.
+func modifiedFiles(t *Task[Pipe], m *internal.Mapping) ([]string, error) {
+	history, err := m.State.History()
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+
+	// the revisions that are applied while pinned are not recorded
+	entry := history[len(history)-1]
+	if pin := m.State.Pinned(); pin != nil {
+		for _, e := range history {
+			if e.Revision == pin.Revision {
+				entry = e
+			}
+		}
+	}
+
+	applied := entry.Files
+	if applied == nil {
+		return nil, nil
+	}
+
+	// the target directory might contain unrelated files, like the state file
+	current := operations.Manifest{}
+	if _, err := os.Stat(currentDirectory(t, m)); err == nil {
+		manifest, err := operations.NewManifest(currentDirectory(t, m) + string(filepath.Separator))
+		if err != nil {
+			return nil, err
+		}
+
+		for path := range applied {
+			if entry, ok := manifest[path]; ok {
+				current[path] = entry
+			}
+		}
+	}
+
+	modified := applied.Changed(current)
+	modified = append(modified, applied.Removed(current)...)
+
+	return modified, nil
+}
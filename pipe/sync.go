@@ -8,95 +8,255 @@ import (
 	"slices"
 	"strings"
 
-	glob "github.com/bmatcuk/doublestar/v4"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/ignore"
 	"gitlab.kilic.dev/docker/beamer/internal/operations"
 	. "gitlab.kilic.dev/libraries/plumber/v5"
 	"golang.org/x/sync/errgroup"
 )
 
+// stage is a mapping that is rendered into its staging directory and waits to be applied.
+type stage struct {
+	mapping   *internal.Mapping
+	directory string
+	files     []string
+	rendered  []string
+	manifest  operations.Manifest
+}
+
 func Workflow(tl *TaskList[Pipe]) *Task[Pipe] {
 	return tl.CreateTask("workflow").
 		ShouldDisable(func(t *Task[Pipe]) bool {
-			return !t.Pipe.Ctx.State.IsDirty() && !t.Pipe.Config.ForceWorkflow
+			return !slices.ContainsFunc(t.Pipe.Ctx.Mappings, func(m *internal.Mapping) bool {
+				return m.State.IsDirty()
+			}) && !t.Pipe.Config.ForceWorkflow && !t.Pipe.DryRun
 		}).
 		Set(func(t *Task[Pipe]) error {
-			ignored, err := parseIgnoreFile(t)
-			if err != nil {
-				return err
-			}
-			t.Log.Debugf("Ignoring patterns: %v", ignored)
+			// every mapping is rendered and validated before any of them is applied, so a failure keeps all the targets intact
+			stages := []*stage{}
+			defer func() {
+				for _, s := range stages {
+					os.RemoveAll(s.directory)
+				}
+			}()
 
-			files, err := walkdir(t, ignored)
-			if err != nil {
-				return err
-			}
-			t.Log.Debugf("Files to process: %v", files)
+			for _, m := range t.Pipe.Ctx.Mappings {
+				if !m.State.IsDirty() && !t.Pipe.Config.ForceWorkflow && !t.Pipe.DryRun {
+					t.Log.Debugf("Mapping is up-to-date, skipping: %s", m)
 
-			// create directories
-			if err := ensureDirs(t, files); err != nil {
-				return err
+					continue
+				} else if pin := m.State.Pinned(); pin != nil && pin.Snapshot != "" {
+					t.Log.Infof("Mapping is pinned to a snapshot, skipping: %s -> %s", m, pin)
+
+					continue
+				}
+
+				s, err := stageMapping(t, m)
+				if s != nil {
+					stages = append(stages, s)
+				}
+				if err != nil {
+					return err
+				}
 			}
 
-			// process files
+			for _, s := range stages {
+				// the mappings that are already applied are kept, but the rest is not started after a termination
+				if err := t.Pipe.Ctx.Context.Err(); err != nil {
+					return fmt.Errorf("Cycle is cancelled before applying the mapping: %s -> %w", s.mapping, err)
+				}
 
-			g := errgroup.Group{}
-			for _, path := range files {
-				g.Go(func() error {
-					return processFile(t, path)
-				})
+				if err := applyMapping(t, s); err != nil {
+					return err
+				}
 			}
 
-			err = g.Wait()
+			return nil
+		})
+}
+
+// stageMapping renders the files of the mapping into a staging directory and validates them, without touching the target directory.
+func stageMapping(t *Task[Pipe], m *internal.Mapping) (*stage, error) {
+	t.Log.Debugf("Processing mapping: %s -> %s", m.RootDirectory, m.TargetDirectory)
+
+	ignored, err := parseIgnoreFile(t, m)
+	if err != nil {
+		return nil, err
+	}
+	t.Log.Debugf("Ignoring patterns: %v", ignored.Patterns())
+
+	filter, err := ignore.NewFilter(ignored, m.Include, m.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	t.Log.Debugf("Filtering with include patterns %v and exclude patterns %v", m.Include, m.Exclude)
+
+	files, err := walkdir(t, m, filter)
+	if err != nil {
+		return nil, err
+	}
+	t.Log.Debugf("Files to process: %v", files)
+
+	// render the files into the staging directory, so that nothing is written to the target before the validation
+	staging, err := createStagingDirectory(t, m)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stage{
+		mapping:   m,
+		directory: staging,
+		files:     files,
+		rendered:  make([]string, len(files)),
+	}
+
+	data, err := NewTemplateData(t, m)
+	if err != nil {
+		return s, err
+	}
+
+	g := errgroup.Group{}
+	for i, path := range files {
+		g.Go(func() error {
+			rel, err := renderFile(t, m, data, staging, path)
 			if err != nil {
 				return err
 			}
 
+			s.rendered[i] = rel
+
 			return nil
 		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return s, err
+	}
+
+	if t.Pipe.Config.Atomic == ATOMIC_SWAP {
+		if err := stageDirectoryModes(t, m, staging, files); err != nil {
+			return s, err
+		}
+	}
+
+	if err := validate(t, staging, s.rendered); err != nil {
+		return s, err
+	}
+
+	if !t.Pipe.DryRun {
+		if s.manifest, err = operations.NewManifest(staging); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
 }
 
-func parseIgnoreFile(t *Task[Pipe]) ([]string, error) {
-	ignored := []string{
-		fmt.Sprintf("**/%s", t.Pipe.Config.IgnoreFile),
-		".git/**",
+// applyMapping brings the target directory of the mapping to the staged files.
+func applyMapping(t *Task[Pipe], s *stage) error {
+	m := s.mapping
+
+	// create directories
+	if err := ensureDirs(t, m, s.files); err != nil {
+		return err
 	}
 
-	if t.Pipe.Config.IgnoreFile == "" {
-		return ignored, nil
+	// apply files
+	g := errgroup.Group{}
+	for _, rel := range s.rendered {
+		g.Go(func() error {
+			return applyFile(t, m, s.directory, rel)
+		})
 	}
 
-	f := operations.NewFile(t.Pipe.WorkingDirectory, t.Pipe.RootDirectory, t.Pipe.Config.IgnoreFile)
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if t.Pipe.Config.Atomic != ATOMIC_SWAP {
+		return recordHistory(t, m, s.manifest, "")
+	}
+
+	if err := recordRemovedFiles(t, m, s.rendered); err != nil {
+		return err
+	}
+
+	if t.Pipe.DryRun {
+		return nil
+	} else if !hasChanges(t, m) {
+		t.Log.Infof("Current revision is already up-to-date, skipping the swap: %s", m)
+
+		snapshot, err := currentRevision(t, m)
+		if err != nil {
+			return err
+		}
+
+		return recordHistory(t, m, s.manifest, snapshot)
+	}
+
+	snapshot, err := swapRevision(t, m, s.directory)
+	if err != nil {
+		return err
+	}
+
+	return recordHistory(t, m, s.manifest, snapshot)
+}
+
+// parseIgnoreFile creates the matcher with the built-in rules and the ignore file in the root directory.
+func parseIgnoreFile(t *Task[Pipe], m *internal.Mapping) (*ignore.Matcher, error) {
+	matcher := ignore.NewMatcher()
+
+	if err := matcher.Add("built-in", ".git"); err != nil {
+		return nil, err
+	}
+
+	if m.IgnoreFile == "" {
+		return matcher, nil
+	}
+
+	if err := matcher.Add("built-in", m.IgnoreFile); err != nil {
+		return nil, err
+	}
+
+	if err := readIgnoreFile(t, m, matcher, ""); err != nil {
+		return nil, err
+	}
+
+	return matcher, nil
+}
+
+// readIgnoreFile adds the rules of the ignore file in the given directory relative to the root directory, if it exists.
+func readIgnoreFile(t *Task[Pipe], m *internal.Mapping, matcher *ignore.Matcher, dir string) error {
+	f := operations.NewFile(t.Pipe.WorkingDirectory, m.RootDirectory, dir, m.IgnoreFile)
 
 	if !f.IsFile() {
 		t.Log.Debugf("Ignore file not found: %s", f.Abs())
 
-		return ignored, nil
+		return nil
 	}
 
 	lines, err := f.ReadLines()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	ignored = append(ignored, lines...)
-
-	return ignored, nil
+	return matcher.AddLines(dir, filepath.Join(dir, m.IgnoreFile), lines)
 }
 
-func walkdir(t *Task[Pipe], ignored []string) ([]string, error) {
+// walkdir collects the files in the root directory relative to the working directory, where the ignored directories are pruned.
+func walkdir(t *Task[Pipe], m *internal.Mapping, filter *ignore.Filter) ([]string, error) {
 	files := []string{}
 
-	f := operations.NewFile(t.Pipe.WorkingDirectory, t.Pipe.RootDirectory)
+	root := operations.NewFile(t.Pipe.WorkingDirectory, m.RootDirectory)
 
-	t.Log.Debugf("Walking from source directory: %s", f.Abs())
+	t.Log.Debugf("Walking from source directory: %s", root.Abs())
 
-	g := errgroup.Group{}
 	err := filepath.WalkDir(
-		f.Abs(),
+		root.Abs(),
 		func(abs string, d fs.DirEntry, e error) error {
 			if e != nil {
 				return fmt.Errorf("Error walking: %s -> %w", abs, e)
-			} else if d.IsDir() {
+			} else if abs == root.Abs() {
 				return nil
 			}
 
@@ -107,15 +267,36 @@ func walkdir(t *Task[Pipe], ignored []string) ([]string, error) {
 				return err
 			}
 
-			for _, pattern := range ignored {
-				match, err := glob.PathMatch(pattern, path)
-				if err != nil {
-					return err
-				} else if match {
-					t.Log.Debugf("Ignoring: %s with /%s/", path, pattern)
+			rel, err := f.RelTo(root.Abs())
+			if err != nil {
+				return err
+			}
+
+			decision := filter.Match(rel, d.IsDir())
+			if decision.Ignored {
+				t.Log.Debugf("Ignoring: %s with %s", path, decision.Rule)
+
+				// recorded as the path in the target like the other changes, where the file would have been applied
+				t.Pipe.Ctx.Changes.Add(internal.Change{
+					Action: internal.CHANGE_ACTION_IGNORED,
+					Path:   operations.NewFile(currentDirectory(t, m), rel).Abs(),
+				})
 
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			t.Log.Debugf("Including: %s with %s", path, decision.Rule)
+
+			if d.IsDir() {
+				if m.IgnoreFile == "" {
 					return nil
 				}
+
+				return readIgnoreFile(t, m, filter.Matcher(), rel)
 			}
 
 			files = append(files, path)
@@ -127,15 +308,10 @@ func walkdir(t *Task[Pipe], ignored []string) ([]string, error) {
 		return nil, err
 	}
 
-	err = g.Wait()
-	if err != nil {
-		return nil, err
-	}
-
 	return files, nil
 }
 
-func ensureDirs(t *Task[Pipe], files []string) error {
+func ensureDirs(t *Task[Pipe], m *internal.Mapping, files []string) error {
 	g := errgroup.Group{}
 
 	dirs := []string{}
@@ -148,11 +324,11 @@ func ensureDirs(t *Task[Pipe], files []string) error {
 	for _, dir := range dirs {
 		g.Go(func() error {
 			source := operations.NewFile(t.Pipe.WorkingDirectory, dir)
-			rel, err := filepath.Rel(t.Pipe.RootDirectory, fmt.Sprintf("/%s", dir))
+			rel, err := filepath.Rel(m.RootDirectory, fmt.Sprintf("/%s", dir))
 			if err != nil {
 				return err
 			}
-			target := operations.NewFile(t.Pipe.TargetDirectory, rel)
+			target := operations.NewFile(currentDirectory(t, m), rel)
 
 			if !source.IsDir() {
 				return fmt.Errorf("Source is not a directory anymore: %s", source.Abs())
@@ -169,65 +345,107 @@ func ensureDirs(t *Task[Pipe], files []string) error {
 
 			t.Log.Debugf("Directory needed in target: %s with %s in %s", target.Rel(), stat.Mode().Perm(), target.Cwd())
 
-			return target.Mkdirp(stat.Mode())
+			t.Pipe.Ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_MKDIR,
+				Path:   target.Abs(),
+				Mode:   stat.Mode().Perm(),
+			})
+
+			if !shouldWrite(t) {
+				return nil
+			}
+
+			if err := target.Mkdirp(stat.Mode()); err != nil {
+				return err
+			}
+
+			return target.Chown(m.Owner)
 		})
 	}
 
 	return g.Wait()
 }
 
-func processFile(t *Task[Pipe], path string) error {
+// renderFile writes the source file into the staging directory, where the templates are rendered, and returns its path relative to the target directory.
+func renderFile(t *Task[Pipe], m *internal.Mapping, data *TemplateData, staging string, path string) (string, error) {
 	sf := operations.NewFile(t.Pipe.WorkingDirectory, path)
-	rel, err := filepath.Rel(t.Pipe.RootDirectory, fmt.Sprintf("/%s", path))
+	rel, err := filepath.Rel(m.RootDirectory, fmt.Sprintf("/%s", path))
 	if err != nil {
-		return err
+		return "", err
 	}
-	tf := operations.NewFile(t.Pipe.TargetDirectory, rel)
-
-	t.Log.Debugf("Processing: %s -> %s", sf.Abs(), tf.Abs())
 
 	if sf.IsDir() {
-		return fmt.Errorf("Source is a directory: %s", sf.Abs())
-	} else if tf.IsDir() {
-		return fmt.Errorf("Target is a directory: %s", tf.Abs())
+		return "", fmt.Errorf("Source is a directory: %s", sf.Abs())
 	}
 
-	//nolint:nestif
-	if slices.Contains(t.Pipe.TemplateFiles, tf.Ext()) {
-		f, err := sf.ReadFile()
-		if err != nil {
-			return err
-		}
+	ss, err := sf.Stat()
+	if err != nil {
+		return "", err
+	}
 
-		template, err := InlineTemplate(string(f), struct{}{})
-		if err != nil {
-			return fmt.Errorf("Can not inline template for file: %s -> %w", sf.Abs(), err)
+	if !slices.Contains(m.TemplateFiles, sf.Ext()) {
+		nf := operations.NewFile(staging, rel)
+		if err := operations.NewFile(nf.Cwd()).Mkdirp(0700); err != nil {
+			return "", err
 		}
 
-		ss, err := sf.Stat()
-		if err != nil {
-			return err
-		}
+		t.Log.Debugf("Staging: %s -> %s", sf.Abs(), nf.Abs())
 
-		// change the source file to the templated file
-		path := strings.TrimSuffix(rel, sf.Ext())
+		return rel, sf.CopyTo(nf)
+	}
 
-		temp, err := os.CreateTemp("", fmt.Sprintf("beamer-%s", filepath.Base(path)))
-		if err != nil {
-			return err
-		}
-		defer os.Remove(temp.Name())
+	f, err := sf.ReadFile()
+	if err != nil {
+		return "", err
+	}
 
-		nf := operations.NewFile(temp.Name())
-		if err := nf.WriteFile([]byte(template), ss.Mode().Perm()); err != nil {
-			return err
-		}
+	template, err := InlineTemplate(string(f), data.ForFile(rel, strings.TrimSuffix(rel, sf.Ext())))
+	if err != nil {
+		return "", fmt.Errorf("Can not inline template for file: %s -> %w", sf.Abs(), err)
+	}
+
+	// change the source file to the templated file
+	rel = strings.TrimSuffix(rel, sf.Ext())
+
+	nf := operations.NewFile(staging, rel)
+	if err := operations.NewFile(nf.Cwd()).Mkdirp(0700); err != nil {
+		return "", err
+	}
+
+	if err := nf.WriteFile([]byte(template), ss.Mode().Perm()); err != nil {
+		return "", err
+	}
+	// the mode of an existing file is not changed by writing it
+	if err := nf.Chmod(ss.Mode().Perm()); err != nil {
+		return "", err
+	}
+
+	tf := operations.NewFile(currentDirectory(t, m), rel)
+
+	t.Log.Debugf("Templated file: %s (staged as %s) -> %s", sf.Abs(), nf.Abs(), tf.Abs())
+
+	t.Pipe.Ctx.Changes.Add(internal.Change{
+		Action: internal.CHANGE_ACTION_TEMPLATED,
+		Path:   tf.Abs(),
+	})
+
+	return rel, nil
+}
+
+// applyFile brings the file in the target directory to the staged one.
+func applyFile(t *Task[Pipe], m *internal.Mapping, staging string, rel string) error {
+	sf := operations.NewFile(staging, rel)
+	tf := operations.NewFile(currentDirectory(t, m), rel)
 
-		tf = operations.NewFile(t.Pipe.TargetDirectory, path)
+	t.Log.Debugf("Processing: %s -> %s", sf.Abs(), tf.Abs())
 
-		t.Log.Debugf("Templated file: %s (from temp %s) -> %s", sf.Abs(), nf.Abs(), tf.Abs())
+	if tf.IsDir() {
+		return fmt.Errorf("Target is a directory: %s", tf.Abs())
+	}
 
-		sf = nf
+	ss, err := sf.Stat()
+	if err != nil {
+		return err
 	}
 
 	if tf.Exists() {
@@ -238,16 +456,105 @@ func processFile(t *Task[Pipe], path string) error {
 			return err
 		}
 
-		if equal {
+		ts, err := tf.Stat()
+		if err != nil {
+			return err
+		}
+
+		// a file that is owned by someone else is updated like a file with a different mode
+		owned := m.Owner == nil || m.Owner.Matches(ts)
+
+		if equal && ts.Mode() == ss.Mode() && owned {
 			t.Log.Debugf("Files are the same, nothing to do: %s -> %s", sf.Abs(), tf.Abs())
 
+			t.Pipe.Ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_UNCHANGED,
+				Path:   tf.Abs(),
+			})
+
 			return nil
+		} else if equal {
+			t.Log.Infof("File mode has changed, updating: %s -> %s with %s", sf.Abs(), tf.Abs(), ss.Mode().Perm())
+
+			t.Pipe.Ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_CHMOD,
+				Path:   tf.Abs(),
+				Mode:   ss.Mode().Perm(),
+			})
+
+			if !shouldWrite(t) {
+				return nil
+			}
+
+			if err := tf.MatchModeWith(sf); err != nil {
+				return err
+			}
+
+			return tf.Chown(m.Owner)
 		}
 
 		t.Log.Infof("File has changed, updating: %s -> %s", sf.Abs(), tf.Abs())
+
+		change := internal.Change{
+			Action: internal.CHANGE_ACTION_UPDATE,
+			Path:   tf.Abs(),
+		}
+
+		if t.Pipe.DryRun {
+			diff, err := diffFiles(tf, sf)
+			if err != nil {
+				return err
+			}
+			change.Diff = diff
+		}
+
+		t.Pipe.Ctx.Changes.Add(change)
+
+		if ts.Mode() != ss.Mode() || !owned {
+			t.Pipe.Ctx.Changes.Add(internal.Change{
+				Action: internal.CHANGE_ACTION_CHMOD,
+				Path:   tf.Abs(),
+				Mode:   ss.Mode().Perm(),
+			})
+		}
 	} else {
 		t.Log.Debugf("File already does not exists copying to target: %s", tf.Abs())
+
+		t.Pipe.Ctx.Changes.Add(internal.Change{
+			Action: internal.CHANGE_ACTION_CREATE,
+			Path:   tf.Abs(),
+			Mode:   ss.Mode().Perm(),
+		})
+	}
+
+	if !shouldWrite(t) {
+		return nil
+	} else if t.Pipe.Config.Atomic == ATOMIC_FILE {
+		return sf.CopyAtomicTo(tf, m.Owner)
+	}
+
+	if err := sf.CopyTo(tf); err != nil {
+		return err
+	}
+
+	return tf.Chown(m.Owner)
+}
+
+// diffFiles returns the unified diff between the current and the next content of a file, binary files are only marked as different.
+func diffFiles(current *operations.File, next *operations.File) (string, error) {
+	from, err := current.ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	to, err := next.ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	if !operations.IsText(from) || !operations.IsText(to) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", current.Abs(), next.Abs()), nil
 	}
 
-	return sf.CopyTo(tf)
+	return operations.UnifiedDiff(current.Abs(), next.Abs(), from, to), nil
 }
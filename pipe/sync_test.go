@@ -0,0 +1,75 @@
+package pipe
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/ignore"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+func TestWalkdirIgnored(t *testing.T) {
+	working := t.TempDir()
+
+	for path, content := range map[string]string{"a.conf": "a", "b.log": "b", "nested/c.conf": "c", "nested/d.log": "d"} {
+		f := filepath.Join(working, path)
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(f, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		atomic  AtomicMode
+		current string
+	}{
+		{name: "target directory", atomic: ATOMIC_NONE, current: "target"},
+		{name: "current revision of the atomic swap", atomic: ATOMIC_SWAP, current: filepath.Join("target", ATOMIC_CURRENT_LINK)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "target")
+
+			task := &Task[Pipe]{
+				Log: logrus.NewEntry(logrus.New()),
+				Pipe: &Pipe{
+					ServiceFlags: internal.ServiceFlags{WorkingDirectory: working},
+					Ctx:          Ctx{Changes: internal.NewChanges()},
+					Config:       Config{Atomic: test.atomic},
+				},
+			}
+			m := &internal.Mapping{Name: "default", RootDirectory: "/", TargetDirectory: target}
+
+			filter, err := ignore.NewFilter(ignore.NewMatcher(), nil, []string{"*.log"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := walkdir(task, m, filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if expected := []string{"a.conf", "nested/c.conf"}; !slices.Equal(files, expected) {
+				t.Fatalf("expected files %v, got %v", expected, files)
+			}
+
+			// the ignored files are recorded in the target like the other changes, not relative to the working directory
+			current := filepath.Join(filepath.Dir(target), test.current)
+			expected := []string{filepath.Join(current, "b.log"), filepath.Join(current, "nested", "d.log")}
+
+			if ignored := task.Pipe.Ctx.Changes.Paths(internal.CHANGE_ACTION_IGNORED); !slices.Equal(ignored, expected) {
+				t.Fatalf("expected ignored %v, got %v", expected, ignored)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package pipe
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"gitlab.kilic.dev/docker/beamer/internal"
 	"gitlab.kilic.dev/docker/beamer/internal/adapter"
@@ -14,31 +17,104 @@ import (
 func Setup(tl *TaskList[Pipe]) *Task[Pipe] {
 	return tl.CreateTask("setup").
 		Set(func(t *Task[Pipe]) error {
+			mappings, err := setupMappings(t)
+			if err != nil {
+				return err
+			}
+
 			ctx := &internal.ServiceCtx{
 				Log:              t.Log,
 				WorkingDirectory: t.Pipe.WorkingDirectory,
-				TargetDirectory:  t.Pipe.TargetDirectory,
-				RootDirectory:    t.Pipe.RootDirectory,
+				Mappings:         mappings,
 				Flags: &internal.ServiceFlags{
-					ForceSync:                  t.Pipe.ForceSync,
-					SyncDelete:                 t.Pipe.SyncDelete,
-					SyncDeleteEmptyDirectories: t.Pipe.SyncDeleteEmptyDirectories,
-					TemplateFiles:              t.Pipe.TemplateFiles,
+					ForceSync: t.Pipe.ForceSync,
+					DryRun:    t.Pipe.DryRun,
 				},
+				Changes: internal.NewChanges(),
+			}
+			t.Pipe.Ctx.Mappings = ctx.Mappings
+			t.Pipe.Ctx.Changes = ctx.Changes
+
+			if t.Pipe.DryRun {
+				t.Log.Warnln("Running in dry run mode, the target directory will not be changed.")
 			}
-			ctx.State = internal.NewState(ctx, filepath.Join(t.Pipe.TargetDirectory, t.Pipe.Config.StateFile))
-			t.Pipe.Ctx.State = ctx.State
 
-			var err error
+			for _, m := range ctx.Mappings {
+				m.State = internal.NewState(ctx, filepath.Join(m.TargetDirectory, m.StateFile)).
+					SetHistoryLimit(t.Pipe.Config.History)
+
+				t.Log.Infof("Using mapping: %s -> %s", m.RootDirectory, m.TargetDirectory)
+
+				if _, err := m.State.Refresh(); err != nil {
+					return err
+				} else if pin := m.State.Pinned(); pin != nil {
+					t.Log.Warnf("Target of mapping %s is pinned to revision until it is unpinned: %s", m, pin)
+				}
+
+				// every revision only contains the rendered files, so the removed files disappear with the swap
+				if t.Pipe.Config.Atomic == ATOMIC_SWAP {
+					m.SyncDelete = false
+
+					t.Log.Infof("Swapping revisions atomically: %s", currentDirectory(t, m))
+				}
+			}
+
+			if t.Pipe.Config.Atomic == ATOMIC_SWAP {
+				ctx.Flags.ForceSync = false
+			}
+
+			switch {
+			case len(t.Pipe.Config.Layers) > 0:
+				layers, err := parseLayers(t)
+				if err != nil {
+					return err
+				}
+
+				layered, err := adapter.NewLayeredAdapter(tl.Plumber, ctx, layers)
+				if err != nil {
+					return err
+				}
+				a = layered
+
+				// the workflow reads the source from the combined working trees of the layers
+				t.Pipe.WorkingDirectory = layered.WorkingDirectory()
 
-			switch tl.Pipe.Config.Adapter {
-			case ADAPTER_GIT:
+				t.Log.Infof("Using %d layers.", len(layers))
+			case tl.Pipe.Config.Adapter == ADAPTER_GIT:
 				a, err = adapter.NewGitAdapter(tl.Plumber, ctx)
 				if err != nil {
 					return err
 				}
 
 				t.Log.Infof("Using Git adapter.")
+			case tl.Pipe.Config.Adapter == ADAPTER_HTTP:
+				a, err = adapter.NewHttpAdapter(tl.Plumber, ctx)
+				if err != nil {
+					return err
+				}
+
+				t.Log.Infof("Using HTTP adapter.")
+			case tl.Pipe.Config.Adapter == ADAPTER_LOCAL:
+				a, err = adapter.NewLocalAdapter(tl.Plumber, ctx)
+				if err != nil {
+					return err
+				}
+
+				t.Log.Infof("Using local adapter.")
+			case tl.Pipe.Config.Adapter == ADAPTER_OCI:
+				a, err = adapter.NewOciAdapter(tl.Plumber, ctx)
+				if err != nil {
+					return err
+				}
+
+				t.Log.Infof("Using OCI adapter.")
+			case tl.Pipe.Config.Adapter == ADAPTER_S3:
+				a, err = adapter.NewS3Adapter(tl.Plumber, ctx)
+				if err != nil {
+					return err
+				}
+
+				t.Log.Infof("Using S3 adapter.")
 			default:
 				return fmt.Errorf("Adapter %s is not supported", tl.Pipe.Config.Adapter)
 			}
@@ -56,8 +132,31 @@ func Setup(tl *TaskList[Pipe]) *Task[Pipe] {
 				return fmt.Errorf("File comparator %s is not supported", t.Pipe.Config.FileComparator)
 			}
 
-			t.Pipe.Ctx.LockFile = operations.NewLockFile(t.Pipe.TargetDirectory, t.Pipe.Config.LockFile)
-			t.Log.Debugf("Lock file: %s", t.Pipe.Ctx.LockFile.Path())
+			if err := setupHooks(t); err != nil {
+				return err
+			}
+
+			// every target is locked in the same order, so that the instances with overlapping mappings can not deadlock
+			t.Pipe.Ctx.LockFiles = []*operations.LockFile{}
+			for _, m := range t.Pipe.Ctx.Mappings {
+				lock := operations.NewLockFile(m.TargetDirectory, t.Pipe.Config.LockFile).
+					SetTimeout(t.Pipe.Config.LockTimeout).
+					SetStaleAfter(t.Pipe.Config.LockStaleTimeout)
+
+				if slices.ContainsFunc(t.Pipe.Ctx.LockFiles, func(l *operations.LockFile) bool {
+					return l.Path() == lock.Path()
+				}) {
+					continue
+				}
+
+				t.Pipe.Ctx.LockFiles = append(t.Pipe.Ctx.LockFiles, lock)
+				t.Log.Debugf("Lock file: %s", lock.Path())
+			}
+			slices.SortFunc(t.Pipe.Ctx.LockFiles, func(a *operations.LockFile, b *operations.LockFile) int {
+				return strings.Compare(a.Path(), b.Path())
+			})
+
+			t.Pipe.Ctx.Context = context.Background()
 
 			return nil
 		})
@@ -0,0 +1,65 @@
+package pipe
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/adapter"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+type (
+	TemplateData struct {
+		Env       map[string]string
+		Values    map[string]any
+		Source    adapter.Revision
+		File      TemplateFile
+		Timestamp time.Time
+	}
+
+	TemplateFile struct {
+		Source string
+		Target string
+	}
+)
+
+// NewTemplateData collects the data for rendering the templates of the mapping.
+func NewTemplateData(t *Task[Pipe], m *internal.Mapping) (*TemplateData, error) {
+	data := &TemplateData{
+		Env:       map[string]string{},
+		Timestamp: time.Now(),
+	}
+
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		data.Env[key] = value
+	}
+
+	revision, err := a.Revision()
+	if err != nil {
+		return nil, err
+	}
+	data.Source = *revision
+
+	t.Log.Debugf("Template data source revision: %s", revision.Revision)
+
+	values, err := loadTemplateValues(t, m)
+	if err != nil {
+		return nil, err
+	}
+	data.Values = values
+
+	return data, nil
+}
+
+// ForFile returns a copy of the template data for rendering the given file.
+func (d TemplateData) ForFile(source string, target string) TemplateData {
+	d.File = TemplateFile{
+		Source: source,
+		Target: target,
+	}
+
+	return d
+}
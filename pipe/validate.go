@@ -0,0 +1,48 @@
+package pipe
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"gitlab.kilic.dev/docker/beamer/internal/hooks"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+)
+
+// validate runs the validation hooks over the rendered files in the staging directory, where any failure vetoes the revision.
+//
+// The workflow fails before touching the target directory, so the adapter state stays dirty and is not committed.
+func validate(t *Task[Pipe], staging string, files []string) error {
+	if len(t.Pipe.Ctx.Validators) == 0 {
+		return nil
+	}
+
+	event := &hooks.Event{
+		Directory: staging,
+		Files:     []string{},
+	}
+	for _, rel := range files {
+		event.Files = append(event.Files, filepath.Join(staging, rel))
+	}
+	slices.Sort(event.Files)
+
+	if revision, err := a.Revision(); err == nil {
+		event.Revision = revision
+	}
+
+	for _, validator := range t.Pipe.Ctx.Validators {
+		t.Log.Infof("Running validation hook: %s", validator)
+
+		if err := validator.Run(t.Pipe.Ctx.Context, t.Log, event); err != nil {
+			t.Log.Errorf("Validation failed, keeping the previous revision: %s", validator)
+
+			return fmt.Errorf("Validation failed for the rendered files: %s -> %w", validator, err)
+		}
+
+		t.Log.Debugf("Validation hook passed: %s", validator)
+	}
+
+	t.Log.Infof("Rendered files are valid.")
+
+	return nil
+}
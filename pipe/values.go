@@ -0,0 +1,89 @@
+package pipe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gitlab.kilic.dev/docker/beamer/internal"
+	"gitlab.kilic.dev/docker/beamer/internal/operations"
+	. "gitlab.kilic.dev/libraries/plumber/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func loadTemplateValues(t *Task[Pipe], m *internal.Mapping) (map[string]any, error) {
+	values := map[string]any{}
+
+	for _, path := range m.TemplateValues {
+		f := operations.NewFile(t.Pipe.WorkingDirectory, m.RootDirectory, path)
+		if !f.IsFile() {
+			f = operations.NewFile(path)
+		}
+
+		if !f.IsFile() {
+			return nil, fmt.Errorf("Template values file not found in the source or the local filesystem: %s", path)
+		}
+
+		t.Log.Debugf("Loading template values: %s", f.Abs())
+
+		data, err := f.ReadFile()
+		if err != nil {
+			return nil, err
+		}
+
+		parsed := map[string]any{}
+		switch strings.ToLower(f.Ext()) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &parsed)
+		case ".json":
+			err = json.Unmarshal(data, &parsed)
+		case ".toml":
+			err = toml.Unmarshal(data, &parsed)
+		default:
+			return nil, fmt.Errorf("Template values file has an unsupported format, expected one of yaml, json, toml: %s", f.Abs())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Can not parse template values file: %s -> %w", f.Abs(), err)
+		}
+
+		if err := mergeTemplateValues(values, parsed, ""); err != nil {
+			return nil, fmt.Errorf("Can not merge template values file: %s -> %w", f.Abs(), err)
+		}
+	}
+
+	return values, nil
+}
+
+// mergeTemplateValues deep merges the source into the destination, where scalars and lists of the source override the destination.
+func mergeTemplateValues(dest map[string]any, source map[string]any, prefix string) error {
+	for key, value := range source {
+		path := key
+		if prefix != "" {
+			path = fmt.Sprintf("%s.%s", prefix, key)
+		}
+
+		existing, ok := dest[key]
+		if !ok || existing == nil || value == nil {
+			dest[key] = value
+
+			continue
+		}
+
+		em, eok := existing.(map[string]any)
+		vm, vok := value.(map[string]any)
+
+		switch {
+		case eok && vok:
+			if err := mergeTemplateValues(em, vm, path); err != nil {
+				return err
+			}
+		case eok != vok:
+			return fmt.Errorf("Conflicting types for key %s: can not merge %T into %T", path, value, existing)
+		default:
+			dest[key] = value
+		}
+	}
+
+	return nil
+}